@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// abortTick is a local message that makes the primary look for LSNs stuck without quorum
+type abortTick struct{}
+
+// startAbortTimer periodically checks pending requests against the abort deadline. Chain mode
+// never aborts: the tail applies an entry as soon as it logs it, so its slot can't be taken back.
+func (a *Actor) startAbortTimer() {
+	if a.abortAfter <= 0 || a.chainMode() {
+		return
+	}
+	self := a.self
+	go func() {
+		ticker := time.NewTicker(min(a.abortAfter/2, time.Second))
+		defer ticker.Stop()
+		for range ticker.C {
+			a.system.Root.Send(self, &abortTick{})
+		}
+	}()
+}
+
+// handleAbortTick aborts every unapplied LSN whose request has waited past the deadline without
+// reaching quorum, oldest first, so the LSNs queued behind it can be applied
+func (a *Actor) handleAbortTick(ctx actor.Context) {
+	if !a.isPrimary || a.chainMode() {
+		return
+	}
+	stuck := a.Server.ExpiredWithoutQuorum(a.abortAfter, a.subscribers+1, a.lastAppliedLSN.Load())
+	sort.Slice(stuck, func(i, j int) bool { return stuck[i] < stuck[j] })
+	for _, lsn := range stuck {
+		a.abortLSN(ctx, lsn)
+	}
+}
+
+// abortLSN replaces an LSN's entry with a no-op and replicates the Abort. Its client is only failed
+// once a quorum has logged the no-op: until then a backup holding the Write could still win an
+// election and commit it during recovery.
+func (a *Actor) abortLSN(ctx actor.Context, lsn int64) {
+	log.Printf("Primary: Aborting LSN %d, no quorum after %s\n", lsn, a.abortAfter)
+	noop := &Request{Type: "NOOP", LSN: lsn, Term: a.term.Load()}
+
+	a.Mu.Lock()
+	a.Log[lsn] = noop
+	a.Mu.Unlock()
+	if err := a.wal.AppendEntry(noop); err != nil {
+		log.Printf("Primary: Failed to log Abort(LSN=%d) to WAL: %v\n", lsn, err)
+		return
+	}
+
+	reached, exists := a.Server.AbortPendingRequest(lsn, a.subscribers+1)
+	if exists && reached {
+		a.failAborted(lsn)
+	}
+
+	abort := &messages.Abort{Lsn: lsn, Term: a.term.Load()}
+	a.recordAborted(lsn)
+	a.trackAccept(lsn, abort)
+	for _, target := range a.liveTargets() {
+		log.Printf("Primary: Sending Abort(LSN=%d) to %s\n", lsn, target.String())
+		ctx.Request(target, abort)
+	}
+
+	a.pendingMu.Lock()
+	a.pendingCommits[lsn] = noop
+	a.pendingMu.Unlock()
+	a.applyPendingLSNs()
+}
+
+// handleAbortAck counts a backup's ack of an Abort toward the quorum its client is waiting on
+func (a *Actor) handleAbortAck(replica string, lsn int64) {
+	a.recordReplicaAck(lsn, replica, false)
+	if reached, exists := a.Server.RecordAbortAck(lsn, replica, a.subscribers+1); exists && reached {
+		a.failAborted(lsn)
+	}
+}
+
+// failAborted answers the client of an LSN whose Abort a quorum has logged, so no failover can
+// bring the original request back
+func (a *Actor) failAborted(lsn int64) {
+	log.Printf("Primary: Abort of LSN %d reached quorum, failing its request\n", lsn)
+	a.Server.CompletePendingRequest(lsn, &Response{
+		Success: false,
+		Error:   "Request aborted: replicas did not acknowledge in time",
+		Status:  http.StatusServiceUnavailable,
+	})
+}
+
+// applyNoop fills an aborted slot on the primary without touching the store
+func (a *Actor) applyNoop(lsn int64) {
+	a.Mu.Lock()
+	a.lastAppliedLSN.Store(lsn)
+	a.Mu.Unlock()
+	log.Printf("Primary: Applied no-op for aborted LSN %d\n", lsn)
+}
+
+// handleAbort logs a no-op for the slot on a backup and applies it in LSN order
+func (a *Actor) handleAbort(ctx actor.Context, msg *messages.Abort) {
+	log.Printf("%s: Received Abort(LSN=%d) from %s\n", role(a.isPrimary), msg.Lsn, ctx.Sender().String())
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	a.observeLSN(msg.Lsn)
+
+	if msg.Lsn > a.lastAppliedLSN.Load() {
+		noop := &Request{Type: "NOOP", LSN: msg.Lsn, Term: msg.Term}
+		a.Mu.Lock()
+		a.Log[msg.Lsn] = noop
+		a.Mu.Unlock()
+		if err := a.wal.AppendEntry(noop); err != nil {
+			log.Printf("%s: Failed to log Abort(LSN=%d) to WAL, not acking: %v\n", role(a.isPrimary), msg.Lsn, err)
+			return
+		}
+
+		// An Abort is decided by the primary, so it is committed as soon as it is logged
+		a.pendingMu.Lock()
+		a.pendingCommits[msg.Lsn] = noop
+		a.pendingMu.Unlock()
+		a.applyPendingCommitsToBackup()
+	}
+
+	ctx.Request(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn, Term: a.term.Load(), Abort: true})
+}
+
+// isAborted reports whether an LSN already holds a no-op from term or later, which a late Write/Read
+// of that term must not replace. A newer primary's entry does replace it.
+func (a *Actor) isAborted(lsn, term int64) bool {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	req, exists := a.Log[lsn]
+	return exists && req.Type == "NOOP" && req.Term >= term
+}
@@ -3,9 +3,9 @@ package main
 import (
 	"fmt"
 	"log"
-	"math"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"distributed/messages"
 
@@ -14,23 +14,81 @@ import (
 )
 
 type Actor struct {
-	targets        []*actor.PID
-	targetNames    map[string]string
-	system         *actor.ActorSystem
-	subscribers    int
-	remoter        *remote.Remote
-	Mu             sync.Mutex // Guards store + log
-	isPrimary      bool
-	httpPort       int
-	Server         *Server
-	Log            map[int64]*Request // LSN => Request  (key, value) (Note capital L)
-	store          map[string]string  // Requests (key => value)
-	lsn            atomic.Int64       // Monotonically increasing log sequence number
-	serverStarted  bool
-	ctx            actor.Context      // Store context for use in write method
-	lastAppliedLSN atomic.Int64       // Track the last LSN applied to store
-	pendingCommits map[int64]*Request // Queue of commits waiting for previous LSN
-	pendingMu      sync.Mutex         // Guards pendingCommits
+	targets             []*actor.PID
+	targetNames         map[string]string
+	system              *actor.ActorSystem
+	subscribers         int
+	remoter             *remote.Remote
+	Mu                  sync.Mutex // Guards store + log
+	isPrimary           bool
+	httpPort            int
+	Server              *Server
+	Log                 map[int64]*Request // LSN => Request  (key, value) (Note capital L)
+	store               map[string]string  // Requests (key => value)
+	lsn                 atomic.Int64       // Monotonically increasing log sequence number
+	serverStarted       bool
+	ctx                 actor.Context                  // Store context for use in write method
+	lastAppliedLSN      atomic.Int64                   // Track the last LSN applied to store
+	pendingCommits      map[int64]*Request             // Queue of commits waiting for previous LSN
+	pendingMu           sync.Mutex                     // Guards pendingCommits
+	self                *actor.PID                     // This actor's PID, set on Started
+	primaryPID          *actor.PID                     // Primary being followed (backups only)
+	primaryAlive        bool                           // False once the watched primary terminates
+	peers               []*actor.PID                   // Other backups, learned from Membership
+	clusterSize         int                            // Replicas in the cluster including the primary
+	term                atomic.Int64                   // Current election term
+	votedTerm           int64                          // Last term this node granted a vote in
+	votes               int                            // Votes collected while candidate
+	candidate           bool                           // True while running an election
+	electionLog         map[int64]*Request             // Entries past lastAppliedLSN gathered from the votes (candidate only)
+	catchingUp          bool                           // Subscribed but the primary's state transfer hasn't arrived yet
+	subscribedAt        time.Time                      // When this backup last sent Subscribe
+	wal                 *WAL                           // Durable log of entries and commits
+	snapshotEvery       int64                          // Applied LSNs between snapshots (0 disables)
+	snapshotLSN         atomic.Int64                   // LSN of the latest snapshot; Log is truncated at or below it
+	snapshotting        atomic.Bool                    // Guards against overlapping snapshots
+	retransmits         map[int64]*retransmitEntry     // LSN => replicas still owing an ack (primary only)
+	retransmitMu        sync.Mutex                     // Guards retransmits, aborted and replicaApplied
+	aborted             map[int64]bool                 // Aborted LSNs some replica may not have applied yet (primary only)
+	replicaApplied      map[string]int64               // Replica => highest LSN it reported applied (primary only)
+	retransmitInterval  time.Duration                  // Initial resend delay for unacked messages (0 disables)
+	highestSeenLSN      int64                          // Highest LSN heard from the primary (backups only)
+	nackedAt            map[int64]time.Time            // When each missing LSN was last NACKed
+	abortAfter          time.Duration                  // Deadline before an LSN without quorum is aborted (0 disables)
+	configLSN           int64                          // LSN of the latest config change; a new one waits until it is applied
+	config              *Request                       // Latest committed config change, kept in snapshots; guarded by Mu
+	durability          Durability                     // Default durability mode for requests without an X-Durability header
+	leaseDuration       time.Duration                  // How long a heartbeat ack lets the primary read locally (0 disables)
+	leaseAcks           map[string]int64               // Replica => send time of the latest heartbeat it acked (primary only)
+	leaseMu             sync.Mutex                     // Guards leaseAcks
+	lastHeartbeat       time.Time                      // When this backup last promised the primary its lease
+	recoveredLSN        int64                          // Highest LSN re-replicated on election; lease reads wait until it is applied
+	primaryHTTP         string                         // Primary's client-facing host:port, learned from Membership/NewPrimary
+	minLSNWait          time.Duration                  // How long a backup read waits for min_lsn before redirecting
+	readIndexWaiters    []*readIndexWaiter             // ReadIndex requests waiting on a heartbeat quorum (primary only)
+	batchSize           int                            // Max entries per WriteBatch (1 sends every accept on its own)
+	batchLinger         time.Duration                  // How long the first entry of a batch waits for more
+	batch               []*Request                     // Entries waiting to be flushed as a WriteBatch
+	batchMu             sync.Mutex                     // Guards batch
+	batches             map[int64]*sentBatch           // Highest LSN => LSNs of a sent WriteBatch (primary only)
+	commitSent          atomic.Int64                   // Highest commit index piggybacked to backups (primary only)
+	commitNotifyArmed   atomic.Bool                    // A commitTick is pending
+	heartbeatInterval   time.Duration                  // How often heartbeats are sent and failure detectors checked (0 disables)
+	detector            *failureDetector               // Health of the backups (primary) or of the followed primary (backup)
+	replication         string                         // Replication scheme: primary fan-out or chain
+	chainNext           *actor.PID                     // Backup this one forwards accepts to in chain mode (nil at the tail)
+	chainTail           bool                           // True on the last backup of the chain
+	chainLength         int                            // Backups in the chain, learned from Membership
+	versions            map[string]int64               // Key => LSN of the write that last set it; guarded by Mu
+	history             *storeHistory                  // Versions of every key since history.floor; guarded by Mu
+	historyRetention    int64                          // Applied LSNs of history kept for reads at an LSN (0 disables)
+	historyCollected    int64                          // Applied LSN when the history was last pruned
+	tombstones          map[string]int64               // Deleted key => LSN of the delete, until a snapshot compacts it; guarded by Mu
+	merkle              merkleTree                     // Bucket hashes over store, guarded by Mu
+	merkleFrozen        *merkleSnapshot                // Tree at the LSN the current anti-entropy round compares at
+	antiEntropyInterval time.Duration                  // How often the primary compares Merkle trees with backups (0 disables)
+	consistency         map[string]*ReplicaConsistency // Replica => latest anti-entropy outcome (primary only), guarded by Mu
+	lastConsistency     *ReplicaConsistency            // Latest anti-entropy outcome against the primary (backups only)
 	// firstRun       bool               // To track first run for testing
 }
 
@@ -42,17 +100,54 @@ func (a *Actor) Receive(ctx actor.Context) {
 		if a.pendingCommits == nil {
 			a.pendingCommits = make(map[int64]*Request)
 		}
+		if a.retransmits == nil {
+			a.retransmits = make(map[int64]*retransmitEntry)
+		}
+		if a.aborted == nil {
+			a.aborted = make(map[int64]bool)
+		}
+		if a.replicaApplied == nil {
+			a.replicaApplied = make(map[string]int64)
+		}
+		if a.nackedAt == nil {
+			a.nackedAt = make(map[int64]time.Time)
+		}
+		if a.leaseAcks == nil {
+			a.leaseAcks = make(map[string]int64)
+		}
+		if a.batches == nil {
+			a.batches = make(map[int64]*sentBatch)
+		}
+		if a.consistency == nil {
+			a.consistency = make(map[string]*ReplicaConsistency)
+		}
+		a.self = ctx.Self()
+		a.startRetransmitTimer()
+		a.startRepairTimer()
+		a.startAbortTimer()
+		a.startHeartbeatTimer()
+		a.startAntiEntropyTimer()
+		if a.isPrimary {
+			a.clusterSize = a.subscribers + 1
+			a.replicateUnapplied(ctx)
+		}
 
 		if !a.isPrimary {
-			// If backup, Subscribe to the primary actor
+			// If backup, Subscribe to the primary actor and watch it for failover
 			for _, target := range a.targets {
-				ctx.Request(target, &messages.Subscribe{})
+				ctx.Request(target, &messages.Subscribe{LastLsn: a.lastAppliedLSN.Load(), Term: a.term.Load()})
+				ctx.Watch(target)
 			}
+			a.catchingUp = true // Entries restored from the WAL may be from a deposed primary
+			a.subscribedAt = time.Now()
+			a.primaryPID = a.targets[0]
+			a.primaryAlive = true
+			a.detector.heard(a.primaryPID.String())
 		}
 
 		// On all machines, start server only once
 		if a.Server == nil {
-			a.Server = NewServer(a, a.httpPort)
+			a.Server = NewServer(a, a.httpPort, a.minLSNWait)
 		}
 		if !a.serverStarted {
 			a.Server.Start()
@@ -60,14 +155,89 @@ func (a *Actor) Receive(ctx actor.Context) {
 		}
 	case *messages.Subscribe:
 		senderPID := ctx.Sender()
-		log.Printf("%s: Received Subscribe message from %s\n", role(a.isPrimary), senderPID.String())
-		a.targets = append(a.targets, senderPID)
-		name := fmt.Sprintf("Backup%d", len(a.targets))
-		a.targetNames[senderPID.String()] = name
+		log.Printf("%s: Received Subscribe(LastLSN=%d, Term=%d) message from %s\n", role(a.isPrimary), msg.LastLsn, msg.Term, senderPID.String())
+		if !a.acceptReplicaTerm(ctx, msg.Term) {
+			break
+		}
+		if _, known := a.targetNames[senderPID.String()]; !known && len(a.targets) >= a.subscribers {
+			// Not in the configuration; catch it up so it is ready once added through /admin/members
+			log.Printf("%s: %s is not a member, sending state transfer only\n", role(a.isPrimary), senderPID.String())
+			a.sendStateTransfer(ctx, senderPID, msg.LastLsn)
+			break
+		}
+		if _, known := a.targetNames[senderPID.String()]; !known { // Backups re-subscribe after a failover
+			a.targets = append(a.targets, senderPID)
+			name := fmt.Sprintf("Backup%d", len(a.targets))
+			a.targetNames[senderPID.String()] = name
+		}
 		log.Printf("%s: Current targets: %v\n", role(a.isPrimary), a.targets)
 		if len(a.targets) >= a.subscribers { // Expected backups compared to actual
 			log.Printf("%s: All backups have subscribed. Ready to process requests.\n", role(a.isPrimary))
 		}
+		a.broadcastMembership(ctx)
+		a.sendStateTransfer(ctx, senderPID, msg.LastLsn)
+	case *messages.StateTransfer:
+		a.handleStateTransfer(ctx, msg)
+	case *messages.Membership:
+		a.handleMembership(ctx, msg)
+	case *actor.Terminated:
+		a.handleTerminated(ctx, msg)
+	case *messages.RequestVote:
+		a.handleRequestVote(ctx, msg)
+	case *messages.Vote:
+		a.handleVote(ctx, msg)
+	case *electionTimeout:
+		a.handleElectionTimeout(ctx, msg)
+	case *messages.NewPrimary:
+		a.handleNewPrimary(ctx, msg)
+	case *messages.StaleTerm:
+		a.handleStaleTerm(ctx, msg)
+	case *messages.ConfigChange:
+		a.handleConfigChange(ctx, msg)
+	case *localAck:
+		a.handleLocalAck(msg)
+	case *heartbeatTick:
+		a.handleHeartbeatTick(ctx)
+	case *messages.Heartbeat:
+		a.handleHeartbeat(ctx, msg)
+	case *messages.HeartbeatAck:
+		a.handleHeartbeatAck(ctx, msg)
+	case *messages.ReadIndex:
+		a.handleReadIndex(ctx, msg)
+	case *messages.ForwardWrite:
+		a.Server.answerForwardedWrite(a.system, ctx.Sender(), msg)
+	case *antiEntropyTick:
+		a.handleAntiEntropyTick(ctx)
+	case *messages.MerkleRoot:
+		a.handleMerkleRoot(ctx, msg)
+	case *messages.MerkleRequest:
+		a.handleMerkleRequest(ctx, msg)
+	case *messages.MerkleNodes:
+		a.handleMerkleNodes(ctx, msg)
+	case *messages.MerkleKeys:
+		a.handleMerkleKeys(ctx, msg)
+	case *messages.MerkleResult:
+		a.handleMerkleResult(ctx, msg)
+	case *flushBatch:
+		a.handleFlushBatch(ctx)
+	case *messages.WriteBatch:
+		a.handleWriteBatch(ctx, msg)
+	case *commitTick:
+		a.handleCommitTick(ctx)
+	case *retransmitTick:
+		a.handleRetransmitTick(ctx)
+	case *repairTick:
+		a.requestRepair(ctx)
+	case *messages.Nack:
+		a.handleNack(ctx, msg)
+	case *shardsChanged:
+		if a.isPrimary {
+			a.broadcastMembership(ctx)
+		}
+	case *abortTick:
+		a.handleAbortTick(ctx)
+	case *messages.Abort:
+		a.handleAbort(ctx, msg)
 	case *messages.Write:
 		// Step 4) Backup receives write request from primary
 		senderStr := "<unknown>"
@@ -76,51 +246,80 @@ func (a *Actor) Receive(ctx actor.Context) {
 		}
 		log.Printf("%s: Received Write(LSN=%d, Key=%s, Value=%s) from %s\n",
 			role(a.isPrimary), msg.Lsn, msg.Key, msg.Val, senderStr)
-		a.Mu.Lock()                // Guarding Log
-		a.Log[msg.Lsn] = &Request{ // Remember requested LSN in Log
-			Type: "WRITE",
-			Key:  msg.Key,
-			Val:  msg.Val,
-			LSN:  msg.Lsn,
+		if !a.acceptPrimaryTerm(ctx, msg.Term) {
+			break
+		}
+		a.observeLSN(msg.Lsn)
+		req := &Request{
+			Type:   writeType(msg.Type),
+			Key:    msg.Key,
+			Val:    msg.Val,
+			LSN:    msg.Lsn,
+			Term:   msg.Term,
+			Expect: msg.Expect,
+			Ops:    txnFromWire(msg.Txn),
+		}
+		if msg.Lsn > a.lastAppliedLSN.Load() && !a.isAborted(msg.Lsn, msg.Term) { // Retransmits of applied or aborted LSNs are only re-acked
+			a.Mu.Lock()          // Guarding Log
+			a.Log[msg.Lsn] = req // Remember requested LSN in Log
+			a.Mu.Unlock()
+			// Persist before acking so a quorum of acks means the entry survives a crash
+			if err := a.wal.AppendEntry(req); err != nil {
+				log.Printf("%s: Failed to log Write(LSN=%d) to WAL, not acking: %v\n", role(a.isPrimary), msg.Lsn, err)
+				return
+			}
+		}
+		a.commitAtTail(msg.Lsn)
+		a.advanceCommitIndex(ctx, msg.CommitLsn, msg.Aborted)
+		if a.forwardInChain(ctx, msg) {
+			break // Only the tail acks
 		}
-		a.Mu.Unlock()
 		// Only send Ack if we have a valid sender
 		if ctx.Sender() != nil {
-			ctx.Request(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn}) // Tell primary we logged the requested LSN
+			// Tell primary we logged the requested LSN, and how far we have applied
+			ctx.Request(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn, Term: a.term.Load(), AppliedLsn: a.lastAppliedLSN.Load(), Chain: a.chainMode()})
 		}
 	case *messages.Ack:
+		if !a.acceptReplicaTerm(ctx, msg.Term) {
+			break
+		}
 		if a.isPrimary {
 			// Step 5) Primary receives Ack from backup
-			log.Printf("Primary: Received Ack(LSN=%d) from %s\n", msg.Lsn, ctx.Sender().String())
-			acks, exists := a.Server.RecordAck(msg.Lsn)
-
-			if exists && acks >= int(math.Ceil(float64(a.subscribers+1)/2.0)) { // Quorum reached; Quorum is subscribers + primary / 2 rounded up
-				// Check if we can apply this LSN (previous must be applied)
-				lastApplied := a.lastAppliedLSN.Load()
-
-				if msg.Lsn == lastApplied+1 {
-					// We can apply this LSN immediately
-					a.applyLSNToPrimary(msg.Lsn)
-
-					// Now check if any pending LSNs can be applied
-					a.applyPendingLSNs()
-				} else if msg.Lsn > lastApplied+1 {
-					// Queue this for later - previous LSN not applied yet
-					log.Printf("Primary: Queuing LSN %d (waiting for LSN %d to be applied first)\n", msg.Lsn, lastApplied+1)
-					toCom, exist := a.Server.GetPendingRequest(msg.Lsn)
-					if exist {
-						a.pendingMu.Lock()
-						a.pendingCommits[msg.Lsn] = toCom.request
-						a.pendingMu.Unlock()
-					}
-				} else {
-					// LSN already applied, ignore
-					log.Printf("Primary: LSN %d already applied (lastApplied=%d)\n", msg.Lsn, lastApplied)
+			log.Printf("Primary: Received Ack(LSN=%d, Commit=%t, Batch=%t) from %s\n", msg.Lsn, msg.Commit, msg.Batch, ctx.Sender().String())
+			if _, member := a.targetNames[ctx.Sender().String()]; !member {
+				break // Removed or not yet added replicas don't count toward quorum
+			}
+			a.replicaHeard(ctx, msg.AppliedLsn)
+			if msg.AppliedLsn > 0 {
+				a.recordReplicaApplied(ctx.Sender().String(), msg.AppliedLsn)
+			}
+			if msg.Chain {
+				a.handleChainAck(ctx, msg)
+				break
+			}
+			if msg.Commit {
+				a.recordReplicaAck(msg.Lsn, ctx.Sender().String(), true)
+				break
+			}
+			if msg.Abort {
+				a.handleAbortAck(ctx.Sender().String(), msg.Lsn)
+				break
+			}
+			for _, lsn := range a.ackedLSNs(msg) {
+				a.recordReplicaAck(lsn, ctx.Sender().String(), false)
+				reached, exists := a.Server.RecordAck(lsn, ctx.Sender().String(), a.subscribers+1)
+				if exists && reached { // Enough acks for the request's durability mode
+					a.commitLSN(lsn)
 				}
 			}
 		}
 	case *messages.Commit:
 		log.Printf("%s: Received Commit(LSN=%d) from %s\n", role(a.isPrimary), msg.Lsn, ctx.Sender().String())
+		if !a.acceptPrimaryTerm(ctx, msg.Term) || a.catchingUp {
+			break // While catching up the logged entry may be stale; the state transfer re-queues it
+		}
+
+		a.observeLSN(msg.Lsn)
 
 		// Check if we can apply this LSN
 		lastApplied := a.lastAppliedLSN.Load()
@@ -131,46 +330,111 @@ func (a *Actor) Receive(ctx actor.Context) {
 
 			// Check if any queued commits can now be applied
 			a.applyPendingCommitsToBackup()
+			if a.lastAppliedLSN.Load() >= msg.Lsn {
+				a.ackCommit(ctx, msg.Lsn)
+			} else {
+				a.requestRepair(ctx) // Committed but never logged here, the Write was lost
+			}
 		} else if msg.Lsn > lastApplied+1 {
 			// Queue for later
 			log.Printf("%s: Queuing Commit for LSN %d (waiting for LSN %d)\n",
 				role(a.isPrimary), msg.Lsn, lastApplied+1)
 			a.Mu.Lock()
-			if req, exists := a.Log[msg.Lsn]; exists {
+			req, exists := a.Log[msg.Lsn]
+			if exists {
 				a.pendingMu.Lock()
 				a.pendingCommits[msg.Lsn] = req
 				a.pendingMu.Unlock()
 			}
 			a.Mu.Unlock()
+			if exists {
+				a.ackCommit(ctx, msg.Lsn)
+			} else {
+				a.requestRepair(ctx)
+			}
 		} else {
-			// Already applied
+			// Already applied, a retransmit whose earlier ack was lost
 			log.Printf("%s: LSN %d already applied (lastApplied=%d)\n",
 				role(a.isPrimary), msg.Lsn, lastApplied)
+			a.ackCommit(ctx, msg.Lsn)
 		}
 
 	case *messages.Read:
 		log.Printf("%s: Received Read(LSN=%d, Key=%s) from %s\n", role(a.isPrimary), msg.Lsn, msg.Request, ctx.Sender().String())
-		a.Mu.Lock()
-		a.Log[msg.Lsn] = &Request{
+		if !a.acceptPrimaryTerm(ctx, msg.Term) {
+			break
+		}
+		a.observeLSN(msg.Lsn)
+		req := &Request{
 			Type: "READ",
 			Key:  msg.Request,
 			LSN:  msg.Lsn,
+			Term: msg.Term,
+		}
+		if msg.Lsn > a.lastAppliedLSN.Load() && !a.isAborted(msg.Lsn, msg.Term) { // Retransmits of applied or aborted LSNs are only re-acked
+			a.Mu.Lock()
+			a.Log[msg.Lsn] = req
+			a.Mu.Unlock()
+			if err := a.wal.AppendEntry(req); err != nil {
+				log.Printf("%s: Failed to log Read(LSN=%d) to WAL, not acking: %v\n", role(a.isPrimary), msg.Lsn, err)
+				return
+			}
 		}
-		a.Mu.Unlock()
 
-		// Update lastAppliedLSN for READ operations on backups
 		// READs still consume LSN slots and must be tracked for ordering
-		if !a.isPrimary {
-			a.lastAppliedLSN.Store(msg.Lsn)
-			log.Printf("%s: Updated lastAppliedLSN to %d (READ operation)\n", role(a.isPrimary), msg.Lsn)
+		// No Commit follows a READ, so queue it and let it apply once every earlier LSN has
+		if !a.isPrimary && msg.Lsn > a.lastAppliedLSN.Load() && !a.isAborted(msg.Lsn, msg.Term) {
+			a.pendingMu.Lock()
+			a.pendingCommits[msg.Lsn] = req
+			a.pendingMu.Unlock()
+			a.applyPendingCommitsToBackup()
+		}
+		a.commitAtTail(msg.Lsn)
+		a.advanceCommitIndex(ctx, msg.CommitLsn, msg.Aborted)
+		if a.forwardInChain(ctx, msg) {
+			break // Only the tail acks
 		}
 
-		ctx.Send(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn}) // Ack but expect no commit msg back
+		ctx.Request(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn, Term: a.term.Load(), AppliedLsn: a.lastAppliedLSN.Load(), Chain: a.chainMode()}) // Ack but expect no commit msg back
+	}
+}
+
+// commitLSN applies an LSN whose durability requirement is met, or queues it behind earlier LSNs
+func (a *Actor) commitLSN(lsn int64) {
+	// Check if we can apply this LSN (previous must be applied)
+	lastApplied := a.lastAppliedLSN.Load()
+
+	if lsn == lastApplied+1 {
+		// We can apply this LSN immediately
+		a.applyLSNToPrimary(lsn)
+
+		// Now check if any pending LSNs can be applied
+		a.applyPendingLSNs()
+	} else if lsn > lastApplied+1 {
+		// Queue this for later - previous LSN not applied yet
+		log.Printf("Primary: Queuing LSN %d (waiting for LSN %d to be applied first)\n", lsn, lastApplied+1)
+		toCom, exist := a.Server.GetPendingRequest(lsn)
+		if exist {
+			a.pendingMu.Lock()
+			a.pendingCommits[lsn] = toCom.request
+			a.pendingMu.Unlock()
+		}
+	} else {
+		// LSN already applied, ignore
+		log.Printf("Primary: LSN %d already applied (lastApplied=%d)\n", lsn, lastApplied)
 	}
 }
 
 // applyLSNToPrimary applies a single LSN to the primary's store
 func (a *Actor) applyLSNToPrimary(lsn int64) {
+	a.pendingMu.Lock()
+	queued := a.pendingCommits[lsn]
+	a.pendingMu.Unlock()
+	if queued != nil && queued.Type == "NOOP" {
+		a.applyNoop(lsn)
+		return
+	}
+
 	toCom, exist := a.Server.GetPendingRequest(lsn)
 	if !exist {
 		log.Printf("Primary: Cannot apply LSN %d - not found in pending requests\n", lsn)
@@ -180,27 +444,42 @@ func (a *Actor) applyLSNToPrimary(lsn int64) {
 	a.Mu.Lock()
 	defer a.Mu.Unlock()
 
-	if toCom.request.Type == "WRITE" {
-		// Send commit to backups
-		for _, target := range a.targets {
-			log.Printf("Primary: Sending Commit(LSN=%d) to %s\n", lsn, target.String())
-			a.ctx.Send(target, &messages.Commit{Lsn: lsn})
+	if isMutation(toCom.request.Type) || toCom.request.Type == "CONFIG" {
+		if err := a.wal.AppendCommit(lsn); err != nil {
+			log.Printf("Primary: Failed to log Commit(LSN=%d) to WAL: %v\n", lsn, err)
 		}
 
-		// Apply to store
-		a.store[toCom.request.Key] = toCom.request.Val
-		log.Printf("Primary: Applied LSN %d (Key=%s, Value=%s) to store\n", lsn, toCom.request.Key, toCom.request.Val)
+		// Backups learn of the commit from the commit index on the next Write or Heartbeat;
+		// a separate Commit is only sent if one of them doesn't report it applied in time
+		a.trackCommit(lsn, &messages.Commit{Lsn: lsn, Term: a.term.Load()})
 
-		// Update last applied
-		a.lastAppliedLSN.Store(lsn)
-
-		// Send response to client
-		a.Server.CompletePendingRequest(lsn, &Response{
+		resp := &Response{
 			Success: true,
 			Key:     toCom.request.Key,
 			Value:   toCom.request.Val,
 			Error:   "",
-		})
+		}
+		if toCom.request.Type == "CONFIG" {
+			a.applyConfig(a.ctx, toCom.request)
+		} else if failed := applyMutation(a, toCom.request, lsn); failed != nil {
+			// Committed, but its condition failed at this LSN on every replica alike
+			log.Printf("Primary: LSN %d not applied: %s\n", lsn, failed.Error)
+			resp = failed
+		} else if toCom.request.Type == "TXN" {
+			resp.Version = lsn
+			log.Printf("Primary: Applied LSN %d (Transaction of %d ops) to store\n", lsn, len(toCom.request.Ops))
+		} else if toCom.request.Type == "DELETE" {
+			log.Printf("Primary: Applied LSN %d (Delete Key=%s) to store\n", lsn, toCom.request.Key)
+		} else {
+			resp.Version = lsn
+			log.Printf("Primary: Applied LSN %d (Key=%s, Value=%s) to store\n", lsn, toCom.request.Key, toCom.request.Val)
+		}
+
+		// Update last applied
+		a.lastAppliedLSN.Store(lsn)
+
+		// Send response to client
+		a.Server.CompletePendingRequest(lsn, resp)
 	} else {
 		// Read operation
 		val, exists := a.store[toCom.request.Key]
@@ -214,14 +493,10 @@ func (a *Actor) applyLSNToPrimary(lsn int64) {
 				Key:     toCom.request.Key,
 				Value:   val,
 				Error:   "",
+				Version: a.versions[toCom.request.Key],
 			})
 		} else {
-			a.Server.CompletePendingRequest(lsn, &Response{
-				Success: false,
-				Key:     toCom.request.Key,
-				Value:   "",
-				Error:   "Key not found",
-			})
+			a.Server.CompletePendingRequest(lsn, keyNotFound(toCom.request.Key, a.tombstones))
 		}
 	}
 }
@@ -249,6 +524,10 @@ func (a *Actor) applyPendingLSNs() {
 		delete(a.pendingCommits, nextLSN)
 		a.pendingMu.Unlock()
 	}
+
+	a.scheduleCommitNotify()
+	a.maybeSnapshot()
+	a.maybeCollectHistory()
 }
 
 // applyLSNToBackup applies a single LSN to the backup's store
@@ -263,38 +542,69 @@ func (a *Actor) applyLSNToBackup(lsn int64) {
 	}
 
 	a.Mu.Lock()
-	a.store[req.Key] = req.Val
-	a.Mu.Unlock()
+	switch req.Type {
+	case "WRITE", "DELETE", "TXN":
+		if err := a.wal.AppendCommit(lsn); err != nil {
+			log.Printf("%s: Failed to log Commit(LSN=%d) to WAL: %v\n", role(a.isPrimary), lsn, err)
+		}
+		if failed := applyMutation(a, req, lsn); failed != nil {
+			log.Printf("%s: LSN %d not applied: %s\n", role(a.isPrimary), lsn, failed.Error)
+		}
+	case "CONFIG":
+		// The new membership itself arrives in the primary's Membership broadcast
+		a.config = req
+		if err := a.wal.AppendCommit(lsn); err != nil {
+			log.Printf("%s: Failed to log Commit(LSN=%d) to WAL: %v\n", role(a.isPrimary), lsn, err)
+		}
+	}
 
-	// Update last applied
+	// Update last applied together with the store so snapshots see a consistent pair
 	a.lastAppliedLSN.Store(lsn)
+	a.Mu.Unlock()
 
 	log.Printf("%s: Applied LSN %d (Key=%s, Value=%s) to store\n", role(a.isPrimary), lsn, req.Key, req.Val)
 }
 
-// applyPendingCommitsToBackup checks and applies any queued commits
+// applyPendingCommitsToBackup applies every queued commit that follows lastAppliedLSN in one pass,
+// holding Mu once and syncing their WAL commit records together
 func (a *Actor) applyPendingCommitsToBackup() {
+	a.Mu.Lock()
+	a.pendingMu.Lock()
+	commits := make([]int64, 0)
 	for {
-		lastApplied := a.lastAppliedLSN.Load()
-		nextLSN := lastApplied + 1
-
-		a.pendingMu.Lock()
-		_, hasPending := a.pendingCommits[nextLSN]
-		a.pendingMu.Unlock()
-
+		nextLSN := a.lastAppliedLSN.Load() + 1
+		req, hasPending := a.pendingCommits[nextLSN]
 		if !hasPending {
 			// No more consecutive LSNs to apply
 			break
 		}
 
-		log.Printf("%s: Applying queued commit for LSN %d\n", role(a.isPrimary), nextLSN)
-		a.applyLSNToBackup(nextLSN)
-
-		// Remove from pending
-		a.pendingMu.Lock()
+		switch req.Type {
+		case "WRITE", "DELETE", "TXN":
+			if failed := applyMutation(a, req, nextLSN); failed != nil {
+				log.Printf("%s: LSN %d not applied: %s\n", role(a.isPrimary), nextLSN, failed.Error)
+			}
+			commits = append(commits, nextLSN)
+		case "CONFIG":
+			// The new membership itself arrives in the primary's Membership broadcast
+			commits = append(commits, nextLSN)
+		}
 		delete(a.pendingCommits, nextLSN)
-		a.pendingMu.Unlock()
+
+		// Update last applied together with the store so snapshots see a consistent pair
+		a.lastAppliedLSN.Store(nextLSN)
+		log.Printf("%s: Applied LSN %d (Key=%s, Value=%s) to store\n", role(a.isPrimary), nextLSN, req.Key, req.Val)
+	}
+	a.pendingMu.Unlock()
+	if len(commits) > 0 {
+		if err := a.wal.AppendCommits(commits); err != nil {
+			log.Printf("%s: Failed to log Commits(LSN=%d-%d) to WAL: %v\n", role(a.isPrimary), commits[0], commits[len(commits)-1], err)
+		}
 	}
+	a.Mu.Unlock()
+
+	a.maybeSnapshot()
+	a.maybeCollectHistory()
 }
 
 func (a *Actor) write(req *Request) {
@@ -305,28 +615,46 @@ func (a *Actor) write(req *Request) {
 	// } else {
 	// 	req.LSN = 1
 	// }
+	tempLSN := req.LSN
 	req.LSN = a.lsn.Add(1) // Increment and get new LSN
+	req.Term = a.term.Load()
 
 	// Step 2) Register pending request with correct LSN
-	a.Server.UpdatePendingRequestLSN(-1, req.LSN, req)
+	a.Server.UpdatePendingRequestLSN(tempLSN, req.LSN, req)
 
 	// Step 3) Send initial Accept (Write) message to all backups
+	commitLSN := a.commitIndex()
 	accept := &messages.Write{
-		Lsn: req.LSN,
-		Key: req.Key,
-		Val: req.Val,
+		Type:      req.Type,
+		Expect:    req.Expect,
+		Txn:       txnToWire(req.Ops),
+		Lsn:       req.LSN,
+		Key:       req.Key,
+		Val:       req.Val,
+		Term:      a.term.Load(),
+		CommitLsn: commitLSN,
+		Aborted:   a.abortedThrough(commitLSN),
 	}
 
 	// Log the request in the primary's log
 	a.Mu.Lock()
 	a.Log[req.LSN] = req
 	a.Mu.Unlock()
+	if a.batching() {
+		a.enqueueAccept(req) // Logged to the WAL and sent when the batch is flushed
+		return
+	}
+	if !a.logToWAL(req) {
+		return
+	}
 
-	for _, target := range a.targets {
+	a.trackAccept(req.LSN, accept)
+	for _, target := range a.chainTargets() {
 		log.Printf("%s: Sending Write(LSN=%d, Key=%s, Value=%s) to %s\n",
 			role(a.isPrimary), accept.Lsn, accept.Key, accept.Val, target.String())
 		a.ctx.Request(target, accept)
 	}
+	a.system.Root.Send(a.self, &localAck{lsn: req.LSN}) // Commits async requests, which only need the primary's log write
 }
 
 func (a *Actor) read(req *Request) {
@@ -342,37 +670,70 @@ func (a *Actor) read(req *Request) {
 				Key:     req.Key,
 				Value:   val,
 				Error:   "",
+				LSN:     a.lastAppliedLSN.Load(), // Token for reading at least this state again
+				Version: a.versions[req.Key],
 			})
 		} else {
 			// Key not found
 			log.Printf("Backup: Read Key=%s - not found in store\n", req.Key)
-			a.Server.CompletePendingRequest(req.LSN, &Response{
-				Success: false,
-				Key:     req.Key,
-				Value:   "",
-				Error:   "Key not found",
-			})
+			a.Server.CompletePendingRequest(req.LSN, keyNotFound(req.Key, a.tombstones))
 		}
 	} else {
+		if a.leaseValid() {
+			// A quorum promised not to elect anyone else, so store reflects every committed write
+			a.readUnderLease(req)
+			return
+		}
+
+		tempLSN := req.LSN
 		req.LSN = a.lsn.Add(1)
-		a.Server.UpdatePendingRequestLSN(-1, req.LSN, req)
+		req.Term = a.term.Load()
+		a.Server.UpdatePendingRequestLSN(tempLSN, req.LSN, req)
 
 		// Log the request in the primary's log
 		a.Mu.Lock()
 		a.Log[req.LSN] = req
 		a.Mu.Unlock()
+		if a.batching() {
+			a.enqueueAccept(req)
+			return
+		}
+		if !a.logToWAL(req) {
+			return
+		}
 
+		commitLSN := a.commitIndex()
 		accept := &messages.Read{
-			Lsn:     req.LSN,
-			Request: req.Key,
+			Lsn:       req.LSN,
+			Request:   req.Key,
+			Term:      a.term.Load(),
+			CommitLsn: commitLSN,
+			Aborted:   a.abortedThrough(commitLSN),
 		}
+		a.trackAccept(req.LSN, accept)
 
-		for _, target := range a.targets {
+		for _, target := range a.chainTargets() {
 			log.Printf("Primary: Sending Read(LSN=%d, Key=%s) to %s\n",
 				accept.Lsn, accept.Request, target.String())
 			a.ctx.Request(target, accept)
 		}
+		a.system.Root.Send(a.self, &localAck{lsn: req.LSN})
+	}
+}
+
+// logToWAL persists a primary-side entry, failing the client request if the WAL can't be written
+func (a *Actor) logToWAL(req *Request) bool {
+	if err := a.wal.AppendEntry(req); err != nil {
+		log.Printf("Primary: Failed to log LSN %d to WAL: %v\n", req.LSN, err)
+		a.Server.CompletePendingRequest(req.LSN, &Response{
+			Success: false,
+			Key:     req.Key,
+			Value:   "",
+			Error:   "Failed to persist request",
+		})
+		return false
 	}
+	return true
 }
 
 func role(isPrimary bool) string {
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleAdmin routes /admin/... requests
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request, path string) {
+	log.Printf("Received admin %s request with path: %s", r.Method, path)
+	parts := strings.Split(path, "/")
+
+	switch {
+	case parts[0] == "members" && r.Method == http.MethodGet:
+		s.sendJSON(w, s.replicator.MemberStatus())
+	case parts[0] == "health" && r.Method == http.MethodGet:
+		reporter, ok := s.replicator.(healthReporter)
+		if !ok {
+			s.sendError(w, "Health reporting is not supported by this replicator", http.StatusNotImplemented)
+			return
+		}
+		s.sendJSON(w, reporter.HealthStatus())
+	case parts[0] == "consistency" && r.Method == http.MethodGet:
+		reporter, ok := s.replicator.(consistencyReporter)
+		if !ok {
+			s.sendError(w, "Anti-entropy is not supported by this replicator", http.StatusNotImplemented)
+			return
+		}
+		s.sendJSON(w, reporter.ConsistencyStatus())
+	case parts[0] == "shards" && r.Method == http.MethodGet:
+		// GET: /admin/shards, or /admin/shards?key=<key> to also look up its owner
+		status, ok := s.shardStatus(r.URL.Query().Get("key"))
+		if !ok {
+			s.sendError(w, "Sharding is not enabled", http.StatusNotFound)
+			return
+		}
+		s.sendJSON(w, status)
+	case parts[0] == "shards" && r.Method == http.MethodPost:
+		// POST: /admin/shards with {"id", "primary", "term"}, sent by a group's new primary
+		s.handleShardAnnouncement(w, r)
+	case parts[0] == "members" && r.Method == http.MethodPost:
+		// POST: /admin/members/add/<host:port> or /admin/members/remove/<host:port>
+		if len(parts) != 3 || (parts[1] != configAdd && parts[1] != configRemove) || parts[2] == "" {
+			s.sendError(w, "POST requests require format: /admin/members/{add|remove}/host:port", http.StatusBadRequest)
+			return
+		}
+		s.handleMembershipChange(w, parts[1], parts[2])
+	default:
+		s.sendError(w, "Unknown admin endpoint", http.StatusNotFound)
+	}
+}
+
+// handleMembershipChange replicates a config change through the log and waits for it to commit
+func (s *Server) handleMembershipChange(w http.ResponseWriter, op, address string) {
+	changer, ok := s.replicator.(membershipChanger)
+	if !ok {
+		s.sendError(w, "Membership changes are not supported by this replicator", http.StatusNotImplemented)
+		return
+	}
+	if !s.replicator.IsPrimary() {
+		s.sendError(w, "Only primary can change membership", http.StatusForbidden)
+		return
+	}
+
+	req := &Request{Type: "CONFIG", LSN: s.NextTempLSN()}
+	respChan := s.RegisterPendingRequest(req.LSN, req, DurabilityQuorum) // Config changes always need overlapping quorums
+
+	go changer.ChangeMembership(req, op, address)
+
+	select {
+	case resp := <-respChan:
+		s.sendResponse(w, resp)
+	case <-time.After(requestTimeout):
+		s.sendError(w, "Request timeout", http.StatusRequestTimeout)
+	}
+}
+
+// sendJSON writes an arbitrary JSON body with status 200
+func (s *Server) sendJSON(w http.ResponseWriter, body any) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
+}
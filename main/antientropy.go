@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Outcomes of an anti-entropy round, reported by GET /admin/consistency
+const (
+	consistencyConsistent = "consistent" // Trees matched at the compared LSN
+	consistencyRepaired   = "repaired"   // Buckets diverged and the backup took the primary's keys for them
+	consistencySkipped    = "skipped"    // The backup was at another LSN, so the stores couldn't be compared
+)
+
+// antiEntropyTick is a local message that makes the primary start a round with every backup
+type antiEntropyTick struct{}
+
+// merkleSnapshot is the tree frozen at the LSN a round compares at, so the walk down the tree
+// sees the same hashes while writes keep changing the store
+type merkleSnapshot struct {
+	lsn  int64
+	tree merkleTree
+}
+
+// ReplicaConsistency is the latest anti-entropy round with one replica
+type ReplicaConsistency struct {
+	Address          string `json:"address"`
+	LSN              int64  `json:"lsn"` // LSN the stores were compared at
+	State            string `json:"state"`
+	DivergentBuckets int    `json:"divergent_buckets"`
+	RepairedKeys     int    `json:"repaired_keys"`
+	RepairedTotal    int    `json:"repaired_total"` // Keys repaired over every round since this node started
+	LastCheckedMs    int64  `json:"last_checked_ms"`
+	checkedAt        time.Time
+}
+
+// ConsistencyStatus is the JSON body returned by GET /admin/consistency
+type ConsistencyStatus struct {
+	Node           string                `json:"node"`
+	Role           string                `json:"role"`
+	LastAppliedLSN int64                 `json:"last_applied_lsn"`
+	Root           string                `json:"root"` // This node's Merkle root at LastAppliedLSN
+	Divergent      int                   `json:"divergent"`
+	Replicas       []*ReplicaConsistency `json:"replicas"`
+}
+
+// setKey writes key to store at version and keeps the Merkle tree in step. Caller must hold Mu.
+func (a *Actor) setKey(key, val string, version int64) {
+	old, existed := a.store[key]
+	oldVersion := a.versions[key]
+	a.store[key] = val
+	a.versions[key] = version
+	if a.historyRetention > 0 {
+		a.history.record(key, version, val, false)
+	}
+	a.merkle.set(key, old, oldVersion, existed, val, version)
+	delete(a.tombstones, key)
+}
+
+// startAntiEntropyTimer periodically compares every backup's store against the primary's
+func (a *Actor) startAntiEntropyTimer() {
+	if a.antiEntropyInterval <= 0 {
+		return
+	}
+	self := a.self
+	go func() {
+		ticker := time.NewTicker(a.antiEntropyInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.system.Root.Send(self, &antiEntropyTick{})
+		}
+	}()
+}
+
+// handleAntiEntropyTick freezes the primary's tree at its applied LSN and sends the root to every backup
+func (a *Actor) handleAntiEntropyTick(ctx actor.Context) {
+	if !a.isPrimary {
+		return
+	}
+	a.Mu.Lock()
+	a.merkleFrozen = &merkleSnapshot{lsn: a.lastAppliedLSN.Load(), tree: a.merkle}
+	a.Mu.Unlock()
+
+	root := &messages.MerkleRoot{Term: a.term.Load(), Lsn: a.merkleFrozen.lsn, Root: a.merkleFrozen.tree.root()}
+	for _, target := range a.liveTargets() {
+		ctx.Request(target, root)
+	}
+}
+
+// handleMerkleRoot compares the primary's root against this backup's at the same LSN,
+// and starts walking down the tree if they differ
+func (a *Actor) handleMerkleRoot(ctx actor.Context, msg *messages.MerkleRoot) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	a.Mu.Lock()
+	frozen := &merkleSnapshot{lsn: a.lastAppliedLSN.Load(), tree: a.merkle}
+	a.Mu.Unlock()
+
+	switch {
+	case frozen.lsn != msg.Lsn:
+		a.finishAntiEntropy(ctx, msg.Lsn, consistencySkipped, 0, 0)
+	case frozen.tree.root() == msg.Root:
+		a.finishAntiEntropy(ctx, msg.Lsn, consistencyConsistent, 0, 0)
+	default:
+		log.Printf("%s: Merkle root differs from the primary's at LSN %d, comparing subtrees\n", role(a.isPrimary), msg.Lsn)
+		a.merkleFrozen = frozen
+		ctx.Request(ctx.Sender(), &messages.MerkleRequest{Term: a.term.Load(), Lsn: msg.Lsn, Level: 0, Indexes: []int32{0}})
+	}
+}
+
+// handleMerkleRequest answers a backup walking down the tree: with the children of the requested
+// nodes from the frozen tree, or, for leaf buckets, with the keys they held at the backup's applied LSN
+func (a *Actor) handleMerkleRequest(ctx actor.Context, msg *messages.MerkleRequest) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) || !a.isPrimary {
+		return
+	}
+	if msg.Level == merkleDepth {
+		a.sendMerkleKeys(ctx, msg.Indexes, msg.AppliedLsn)
+		return
+	}
+
+	frozen := a.merkleFrozen
+	if frozen == nil || frozen.lsn != msg.Lsn {
+		log.Printf("Primary: Ignoring MerkleRequest for LSN %d from %s, the round has moved on\n", msg.Lsn, ctx.Sender().String())
+		return
+	}
+	nodes := &messages.MerkleNodes{Term: a.term.Load(), Lsn: msg.Lsn, Level: msg.Level + 1}
+	for _, index := range msg.Indexes {
+		for _, child := range merkleChildren(int(msg.Level), int(index)) {
+			nodes.Indexes = append(nodes.Indexes, int32(child))
+			nodes.Hashes = append(nodes.Hashes, frozen.tree.node(int(msg.Level)+1, child))
+		}
+	}
+	ctx.Request(ctx.Sender(), nodes)
+}
+
+// sendMerkleKeys sends every key in buckets as of the backup's applied LSN, read from the history.
+// Without history back to that LSN it sends them as of the primary's own applied LSN instead,
+// which the backup can only use if it has caught up to exactly that LSN by then.
+func (a *Actor) sendMerkleKeys(ctx actor.Context, buckets []int32, backupLSN int64) {
+	wanted := make(map[int]bool, len(buckets))
+	for _, bucket := range buckets {
+		wanted[int(bucket)] = true
+	}
+	reply := &messages.MerkleKeys{Term: a.term.Load(), Buckets: buckets, Entries: make(map[string]string), Versions: make(map[string]int64)}
+
+	a.Mu.Lock()
+	reply.Lsn = a.lastAppliedLSN.Load()
+	if backupLSN < reply.Lsn && a.historyRetention > 0 && backupLSN >= a.history.floor {
+		reply.Lsn = backupLSN
+		for key := range a.history.keys {
+			if version, exists := a.history.at(key, backupLSN); exists && wanted[merkleBucket(key)] {
+				reply.Entries[key] = version.val
+				reply.Versions[key] = version.lsn
+			}
+		}
+	} else {
+		for key, val := range a.store {
+			if wanted[merkleBucket(key)] {
+				reply.Entries[key] = val
+				reply.Versions[key] = a.versions[key]
+			}
+		}
+	}
+	a.Mu.Unlock()
+	ctx.Request(ctx.Sender(), reply)
+}
+
+// handleMerkleNodes compares the primary's nodes against the frozen tree and asks for what is
+// under the ones that differ
+func (a *Actor) handleMerkleNodes(ctx actor.Context, msg *messages.MerkleNodes) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	frozen := a.merkleFrozen
+	if frozen == nil || frozen.lsn != msg.Lsn || len(msg.Indexes) != len(msg.Hashes) {
+		return
+	}
+
+	differ := make([]int32, 0)
+	for i, index := range msg.Indexes {
+		if frozen.tree.node(int(msg.Level), int(index)) != msg.Hashes[i] {
+			differ = append(differ, index)
+		}
+	}
+	if len(differ) == 0 {
+		a.merkleFrozen = nil
+		a.finishAntiEntropy(ctx, msg.Lsn, consistencyConsistent, 0, 0)
+		return
+	}
+	ctx.Request(ctx.Sender(), &messages.MerkleRequest{Term: a.term.Load(), Lsn: msg.Lsn, Level: msg.Level, Indexes: differ, AppliedLsn: a.lastAppliedLSN.Load()})
+}
+
+// handleMerkleKeys replaces the divergent buckets with the primary's keys, but only while this backup
+// is still at the LSN they were read at. Keys from any other LSN would install versions that later version
+// conditions are checked against before the backup has applied them.
+func (a *Actor) handleMerkleKeys(ctx actor.Context, msg *messages.MerkleKeys) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	frozen := a.merkleFrozen
+	if frozen == nil {
+		return
+	}
+	a.merkleFrozen = nil
+
+	wanted := make(map[int]bool, len(msg.Buckets))
+	for _, bucket := range msg.Buckets {
+		wanted[int(bucket)] = true
+	}
+
+	a.Mu.Lock()
+	if a.lastAppliedLSN.Load() != msg.Lsn {
+		a.Mu.Unlock()
+		a.finishAntiEntropy(ctx, frozen.lsn, consistencySkipped, len(msg.Buckets), 0)
+		return
+	}
+	repaired := 0
+	for key, val := range a.store {
+		if _, kept := msg.Entries[key]; !kept && wanted[merkleBucket(key)] {
+			a.merkle.remove(key, val, a.versions[key])
+			delete(a.store, key)
+			delete(a.versions, key)
+			if a.historyRetention > 0 {
+				a.history.record(key, msg.Lsn, "", true)
+			}
+			repaired++
+		}
+	}
+	for key, val := range msg.Entries {
+		if current, exists := a.store[key]; !exists || current != val || a.versions[key] != msg.Versions[key] {
+			a.setKey(key, val, msg.Versions[key])
+			repaired++
+		}
+	}
+	if repaired > 0 {
+		a.logRepair(msg.Lsn)
+	}
+	a.Mu.Unlock()
+
+	log.Printf("%s: Repaired %d keys in %d divergent buckets from the primary's store at LSN %d\n",
+		role(a.isPrimary), repaired, len(msg.Buckets), msg.Lsn)
+	a.finishAntiEntropy(ctx, frozen.lsn, consistencyRepaired, len(msg.Buckets), repaired)
+}
+
+// logRepair makes a repair survive a restart: a RESET record replaces the store at lsn, as a diverged
+// state transfer does, and the entries logged past lsn are logged again since replay drops them.
+// Caller must hold Mu.
+func (a *Actor) logRepair(lsn int64) {
+	if err := a.wal.AppendSnapshot(lsn, a.store, a.versions, a.config, true); err != nil {
+		log.Printf("%s: Failed to log repaired store to WAL: %v\n", role(a.isPrimary), err)
+		return
+	}
+	unapplied := make([]int64, 0)
+	for logged := range a.Log {
+		if logged > lsn {
+			unapplied = append(unapplied, logged)
+		}
+	}
+	slices.Sort(unapplied)
+	for _, logged := range unapplied {
+		if err := a.wal.AppendEntry(a.Log[logged]); err != nil {
+			log.Printf("%s: Failed to log LSN %d to WAL: %v\n", role(a.isPrimary), logged, err)
+		}
+	}
+}
+
+// finishAntiEntropy records a round's outcome on the backup and reports it to the primary
+func (a *Actor) finishAntiEntropy(ctx actor.Context, lsn int64, state string, divergent, repaired int) {
+	result := &ReplicaConsistency{LSN: lsn, State: state, DivergentBuckets: divergent, RepairedKeys: repaired, RepairedTotal: repaired, checkedAt: time.Now()}
+	a.Mu.Lock()
+	if a.lastConsistency != nil {
+		result.RepairedTotal += a.lastConsistency.RepairedTotal
+	}
+	a.lastConsistency = result
+	a.Mu.Unlock()
+	if a.primaryPID != nil {
+		ctx.Request(a.primaryPID, &messages.MerkleResult{
+			Term:             a.term.Load(),
+			Lsn:              lsn,
+			State:            state,
+			DivergentBuckets: int32(divergent),
+			RepairedKeys:     int32(repaired),
+		})
+	}
+}
+
+// handleMerkleResult records a backup's outcome of the latest round
+func (a *Actor) handleMerkleResult(ctx actor.Context, msg *messages.MerkleResult) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) || !a.isPrimary {
+		return
+	}
+	if msg.State == consistencyRepaired {
+		log.Printf("Primary: %s diverged in %d buckets at LSN %d and repaired %d keys\n",
+			ctx.Sender().String(), msg.DivergentBuckets, msg.Lsn, msg.RepairedKeys)
+	}
+	result := &ReplicaConsistency{
+		LSN:              msg.Lsn,
+		State:            msg.State,
+		DivergentBuckets: int(msg.DivergentBuckets),
+		RepairedKeys:     int(msg.RepairedKeys),
+		RepairedTotal:    int(msg.RepairedKeys),
+		checkedAt:        time.Now(),
+	}
+	a.Mu.Lock()
+	if previous := a.consistency[ctx.Sender().String()]; previous != nil {
+		result.RepairedTotal += previous.RepairedTotal
+	}
+	a.consistency[ctx.Sender().String()] = result
+	a.Mu.Unlock()
+}
+
+// ConsistencyStatus reports the latest anti-entropy round: with every backup on the primary,
+// with the followed primary on a backup
+func (a *Actor) ConsistencyStatus() *ConsistencyStatus {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	status := &ConsistencyStatus{
+		Node:           a.self.Address,
+		Role:           role(a.isPrimary),
+		LastAppliedLSN: a.lastAppliedLSN.Load(),
+		Root:           fmt.Sprintf("%016x", a.merkle.root()),
+		Replicas:       []*ReplicaConsistency{},
+	}
+	report := func(pid *actor.PID, result *ReplicaConsistency) {
+		entry := &ReplicaConsistency{Address: pid.Address, State: "unchecked", LastCheckedMs: -1}
+		if result != nil {
+			*entry = *result
+			entry.Address = pid.Address
+			entry.LastCheckedMs = time.Since(result.checkedAt).Milliseconds()
+			if result.State == consistencyRepaired {
+				status.Divergent++
+			}
+		}
+		status.Replicas = append(status.Replicas, entry)
+	}
+	if a.isPrimary {
+		for _, target := range a.targets {
+			report(target, a.consistency[target.String()])
+		}
+	} else if a.primaryPID != nil {
+		report(a.primaryPID, a.lastConsistency)
+	}
+	return status
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"google.golang.org/protobuf/proto"
+)
+
+// flushBatch is a local message that makes the primary send the open batch
+type flushBatch struct{}
+
+// sentBatch remembers which LSNs a WriteBatch carried so a single ack for its highest LSN covers them all
+type sentBatch struct {
+	lsns   []int64
+	sentAt time.Time
+}
+
+// batching reports whether accepts are coalesced into WriteBatch messages
+func (a *Actor) batching() bool {
+	return a.batchSize > 1
+}
+
+// enqueueAccept adds a logged primary entry to the open batch. The batch is flushed by the actor
+// once it holds batchSize entries or batchLinger after its first entry, whichever comes first.
+func (a *Actor) enqueueAccept(req *Request) {
+	a.batchMu.Lock()
+	a.batch = append(a.batch, req)
+	size := len(a.batch)
+	a.batchMu.Unlock()
+
+	self := a.self
+	switch {
+	case size >= a.batchSize:
+		a.system.Root.Send(self, &flushBatch{})
+	case size == 1:
+		time.AfterFunc(a.batchLinger, func() {
+			a.system.Root.Send(self, &flushBatch{})
+		})
+	}
+}
+
+// handleFlushBatch logs the open batch with one WAL sync and replicates it as a single WriteBatch
+func (a *Actor) handleFlushBatch(ctx actor.Context) {
+	a.batchMu.Lock()
+	batch := a.batch
+	a.batch = nil
+	a.batchMu.Unlock()
+	if len(batch) == 0 || !a.isPrimary {
+		return
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].LSN < batch[j].LSN })
+
+	if err := a.wal.AppendEntries(batch); err != nil {
+		log.Printf("Primary: Failed to log batch of %d entries to WAL: %v\n", len(batch), err)
+		for _, req := range batch {
+			a.Server.CompletePendingRequest(req.LSN, &Response{
+				Success: false,
+				Key:     req.Key,
+				Error:   "Failed to persist request",
+				Status:  http.StatusInternalServerError,
+			})
+		}
+		return
+	}
+
+	term := a.term.Load()
+	commitLSN := a.commitIndex()
+	msg := &messages.WriteBatch{Term: term, CommitLsn: commitLSN, Aborted: a.abortedThrough(commitLSN)}
+	lsns := make([]int64, 0, len(batch))
+	for _, req := range batch {
+		msg.Entries = append(msg.Entries, logEntry(req))
+		lsns = append(lsns, req.LSN)
+		a.trackAccept(req.LSN, acceptMessage(req, term)) // Retransmits fall back to per-LSN messages
+	}
+	highest := lsns[len(lsns)-1]
+	a.pruneBatches()
+	a.batches[highest] = &sentBatch{lsns: lsns, sentAt: time.Now()}
+
+	targets := a.chainTargets()
+	log.Printf("Primary: Sending WriteBatch(LSNs %d-%d, %d entries) to %d backups\n", lsns[0], highest, len(lsns), len(targets))
+	for _, target := range targets {
+		ctx.Request(target, msg)
+	}
+	for _, lsn := range lsns {
+		a.handleLocalAck(&localAck{lsn: lsn})
+	}
+}
+
+// pruneBatches forgets batches that are applied and old enough that any missing ack is being retransmitted per LSN
+func (a *Actor) pruneBatches() {
+	keepFor := max(a.retransmitInterval, time.Second)
+	lastApplied := a.lastAppliedLSN.Load()
+	for highest, batch := range a.batches {
+		if highest <= lastApplied && time.Since(batch.sentAt) > keepFor {
+			delete(a.batches, highest)
+		}
+	}
+}
+
+// ackedLSNs expands a replica's ack into the LSNs it covers
+func (a *Actor) ackedLSNs(msg *messages.Ack) []int64 {
+	if !msg.Batch {
+		return []int64{msg.Lsn}
+	}
+	if batch, exists := a.batches[msg.Lsn]; exists {
+		return batch.lsns
+	}
+	return nil
+}
+
+// acceptMessage builds the single-entry Write, Read or ConfigChange for a logged primary entry
+func acceptMessage(req *Request, term int64) proto.Message {
+	switch req.Type {
+	case "READ":
+		return &messages.Read{Lsn: req.LSN, Request: req.Key, Term: term}
+	case "CONFIG":
+		return configChange(req, term)
+	}
+	return &messages.Write{Type: req.Type, Lsn: req.LSN, Key: req.Key, Val: req.Val, Term: term, Expect: req.Expect, Txn: txnToWire(req.Ops)}
+}
+
+// handleWriteBatch logs every entry of a batch with one WAL sync and acks them with one Ack for the highest LSN
+func (a *Actor) handleWriteBatch(ctx actor.Context, msg *messages.WriteBatch) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) || len(msg.Entries) == 0 {
+		return
+	}
+
+	highest := int64(0)
+	logged := make([]*Request, 0, len(msg.Entries))
+	for _, entry := range msg.Entries {
+		a.observeLSN(entry.Lsn)
+		highest = max(highest, entry.Lsn)
+		if entry.Lsn > a.lastAppliedLSN.Load() && !a.isAborted(entry.Lsn, msg.Term) { // Retransmits of applied or aborted LSNs are only re-acked
+			logged = append(logged, requestFromEntry(entry))
+		}
+	}
+	log.Printf("%s: Received WriteBatch(%d entries, highest LSN=%d) from %s\n",
+		role(a.isPrimary), len(msg.Entries), highest, ctx.Sender().String())
+
+	a.Mu.Lock()
+	for _, req := range logged {
+		a.Log[req.LSN] = req
+	}
+	a.Mu.Unlock()
+	if err := a.wal.AppendEntries(logged); err != nil {
+		log.Printf("%s: Failed to log WriteBatch to WAL, not acking: %v\n", role(a.isPrimary), err)
+		return
+	}
+
+	// READs get no Commit, so they apply once every earlier LSN has
+	a.pendingMu.Lock()
+	for _, req := range logged {
+		if req.Type == "READ" {
+			a.pendingCommits[req.LSN] = req
+		}
+	}
+	a.pendingMu.Unlock()
+	a.applyPendingCommitsToBackup()
+	a.commitAtTail(highest)
+	a.advanceCommitIndex(ctx, msg.CommitLsn, msg.Aborted)
+	if a.forwardInChain(ctx, msg) {
+		return // Only the tail acks
+	}
+
+	ctx.Request(ctx.Sender(), &messages.Ack{Lsn: highest, Batch: true, Term: a.term.Load(), AppliedLsn: a.lastAppliedLSN.Load(), Chain: a.chainMode()})
+}
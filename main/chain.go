@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"google.golang.org/protobuf/proto"
+)
+
+// Replication schemes selected with -replication
+const (
+	replicationPrimary = "primary" // The primary fans every accept out to all backups and commits on quorum
+	replicationChain   = "chain"   // The primary sends to the head of a chain of backups and commits when the tail acks
+)
+
+// DurabilityChain is the only durability in chain mode: a write is durable once it reached the tail,
+// i.e. every backup in the chain logged it
+const DurabilityChain Durability = "chain"
+
+// ParseReplication validates the -replication flag
+func ParseReplication(mode string) (string, error) {
+	switch mode {
+	case replicationPrimary, replicationChain:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown replication mode %q (use primary or chain)", mode)
+}
+
+// chainMode reports whether writes travel down a chain of backups instead of being fanned out
+func (a *Actor) chainMode() bool {
+	return a.replication == replicationChain
+}
+
+// chain is the order accepts travel in: the live backups in membership order, the last one being the tail
+func (a *Actor) chain() []*actor.PID {
+	return a.liveTargets()
+}
+
+// chainCovers reports whether a chain of length backups plus the primary makes up a quorum.
+// A shorter chain can't commit, since a write only it holds could be lost in the next election.
+func chainCovers(length, clusterSize int) bool {
+	return length+1 >= quorumSize(clusterSize)
+}
+
+// chainTargets is who the primary sends a new accept to: only the head in chain mode
+func (a *Actor) chainTargets() []*actor.PID {
+	if !a.chainMode() {
+		return a.liveTargets()
+	}
+	chain := a.chain()
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain[:1]
+}
+
+// handleChainAck commits the LSNs the tail acked: reaching the tail means every backup in
+// the chain logged them, so none of them is owed a retransmit either
+func (a *Actor) handleChainAck(ctx actor.Context, msg *messages.Ack) {
+	chain := a.chain()
+	if len(chain) == 0 || !ctx.Sender().Equal(chain[len(chain)-1]) {
+		log.Printf("Primary: Ignoring chain Ack(LSN=%d) from %s, which is not the tail\n", msg.Lsn, ctx.Sender().String())
+		return
+	}
+	if !chainCovers(len(chain), a.subscribers+1) {
+		log.Printf("Primary: Chain of %d backups is too short to commit LSN %d (quorum %d)\n", len(chain), msg.Lsn, quorumSize(a.subscribers+1))
+		return
+	}
+
+	for _, lsn := range a.ackedLSNs(msg) {
+		for _, member := range chain {
+			a.recordReplicaAck(lsn, member.String(), false)
+		}
+		reached, exists := a.Server.RecordAck(lsn, ctx.Sender().String(), a.subscribers+1)
+		if exists && reached {
+			a.commitLSN(lsn)
+		}
+	}
+}
+
+// forwardInChain passes an accept on to the next backup, keeping the primary as its sender so
+// term fencing and the tail's ack still point at the primary. It reports whether this backup
+// forwarded it, i.e. is not the tail and must not ack.
+func (a *Actor) forwardInChain(ctx actor.Context, msg proto.Message) bool {
+	if !a.chainMode() || a.chainNext == nil {
+		return false
+	}
+	ctx.RequestWithCustomSender(a.chainNext, msg, ctx.Sender())
+	return true
+}
+
+// isCommittingTail reports whether this backup is the tail of a chain that covers a quorum.
+// Reaching it is what commits an entry, so its store is exactly the committed state.
+func (a *Actor) isCommittingTail() bool {
+	return a.chainMode() && !a.isPrimary && a.chainTail && chainCovers(a.chainLength, a.clusterSize)
+}
+
+// commitAtTail applies everything up to lsn as soon as the tail has logged it. That is only safe
+// because chain mode never aborts, so a logged entry is never replaced by a no-op. Entries still
+// missing are usually in the next batch, so holes are left to the repair timer.
+func (a *Actor) commitAtTail(lsn int64) {
+	if a.isCommittingTail() {
+		a.applyLoggedThrough(lsn)
+	}
+}
+
+// chainPeers is the chain in membership broadcasts, empty outside chain mode
+func (a *Actor) chainPeers() []*messages.Peer {
+	if !a.chainMode() {
+		return nil
+	}
+	return membershipPeers(a.chain())
+}
+
+// learnChain records this backup's place in the chain from a Membership broadcast
+func (a *Actor) learnChain(chain []*messages.Peer) {
+	a.chainNext = nil
+	a.chainTail = false
+	a.chainLength = len(chain)
+	for i, peer := range chain {
+		if !actor.NewPID(peer.Address, peer.Id).Equal(a.self) {
+			continue
+		}
+		if i == len(chain)-1 {
+			a.chainTail = true
+		} else {
+			a.chainNext = actor.NewPID(chain[i+1].Address, chain[i+1].Id)
+		}
+	}
+	if a.chainMode() {
+		log.Printf("%s: Chain position updated (length=%d, next=%v, tail=%t)\n", role(a.isPrimary), a.chainLength, a.chainNext, a.chainTail)
+	}
+}
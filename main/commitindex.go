@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// commitNotifyDelay is how long the primary waits for an outgoing Write to carry a new commit index
+// before sending it on a Heartbeat instead
+const commitNotifyDelay = 2 * time.Millisecond
+
+// commitTick is a local message that makes the primary announce a commit index no Write has carried
+type commitTick struct{}
+
+// commitIndex returns the commit index to piggyback on an outgoing message and records it as sent
+func (a *Actor) commitIndex() int64 {
+	index := a.lastAppliedLSN.Load()
+	for {
+		sent := a.commitSent.Load()
+		if sent >= index || a.commitSent.CompareAndSwap(sent, index) {
+			return index
+		}
+	}
+}
+
+// scheduleCommitNotify arms a one-shot Heartbeat for commits applied while no Write is going out
+func (a *Actor) scheduleCommitNotify() {
+	if a.lastAppliedLSN.Load() <= a.commitSent.Load() || !a.commitNotifyArmed.CompareAndSwap(false, true) {
+		return
+	}
+	self := a.self
+	time.AfterFunc(commitNotifyDelay, func() {
+		a.system.Root.Send(self, &commitTick{})
+	})
+}
+
+// handleCommitTick sends a Heartbeat carrying the commit index if nothing else carried it
+func (a *Actor) handleCommitTick(ctx actor.Context) {
+	a.commitNotifyArmed.Store(false)
+	if a.isPrimary && a.lastAppliedLSN.Load() > a.commitSent.Load() {
+		a.handleHeartbeatTick(ctx)
+	}
+}
+
+// advanceCommitIndex applies every logged entry up to the primary's commit index in one pass,
+// asking for repair when some of them never arrived. The LSNs the primary aborted below the index
+// become no-ops first, in case their Abort was lost and the aborted entry is still logged here.
+func (a *Actor) advanceCommitIndex(ctx actor.Context, commitLSN int64, aborted []int64) {
+	if a.isPrimary || commitLSN <= a.lastAppliedLSN.Load() {
+		return
+	}
+	a.observeLSN(commitLSN)
+	a.logAborted(aborted, commitLSN)
+	if !a.applyLoggedThrough(commitLSN) {
+		a.requestRepair(ctx)
+	}
+}
+
+// applyLoggedThrough queues every logged entry after lastAppliedLSN up to lsn and applies those
+// that are consecutive, reporting whether all of them were logged
+func (a *Actor) applyLoggedThrough(lsn int64) bool {
+	if a.catchingUp {
+		return true // The logged entries may be stale until the state transfer arrives
+	}
+	complete := true
+	a.Mu.Lock()
+	a.pendingMu.Lock()
+	for next := a.lastAppliedLSN.Load() + 1; next <= lsn; next++ {
+		if req, exists := a.Log[next]; exists {
+			a.pendingCommits[next] = req
+		} else {
+			complete = false
+		}
+	}
+	a.pendingMu.Unlock()
+	a.Mu.Unlock()
+
+	a.applyPendingCommitsToBackup()
+	return complete
+}
+
+// recordReplicaApplied marks every tracked LSN a replica reports as applied as fully acked,
+// so the primary never has to resend their Commit, and forgets aborts every replica is past
+func (a *Actor) recordReplicaApplied(replica string, applied int64) {
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	for lsn, entry := range a.retransmits {
+		if lsn <= applied {
+			entry.acked[replica] = true
+			entry.commitAcked[replica] = true
+		}
+	}
+
+	a.replicaApplied[replica] = max(a.replicaApplied[replica], applied)
+	slowest := applied
+	for _, target := range a.targets {
+		slowest = min(slowest, a.replicaApplied[target.String()])
+	}
+	for lsn := range a.aborted {
+		if lsn <= slowest {
+			delete(a.aborted, lsn)
+		}
+	}
+}
+
+// recordAborted remembers an aborted LSN until every replica has applied past it
+func (a *Actor) recordAborted(lsn int64) {
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+	a.aborted[lsn] = true
+}
+
+// abortedThrough lists the remembered aborted LSNs up to a commit index, to send along with it
+func (a *Actor) abortedThrough(commitLSN int64) []int64 {
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	var lsns []int64
+	for lsn := range a.aborted {
+		if lsn <= commitLSN {
+			lsns = append(lsns, lsn)
+		}
+	}
+	slices.Sort(lsns)
+	return lsns
+}
+
+// logAborted replaces the entries of aborted LSNs past lastAppliedLSN with no-ops, so a commit
+// index passing them can't apply a write whose Abort never arrived
+func (a *Actor) logAborted(aborted []int64, commitLSN int64) {
+	term := a.term.Load()
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	for _, lsn := range aborted {
+		if lsn <= a.lastAppliedLSN.Load() || lsn > commitLSN {
+			continue
+		}
+		if req, exists := a.Log[lsn]; exists && req.Type == "NOOP" {
+			continue
+		}
+		noop := &Request{Type: "NOOP", LSN: lsn, Term: term}
+		a.Log[lsn] = noop
+		if err := a.wal.AppendEntry(noop); err != nil {
+			log.Printf("%s: Failed to log Abort(LSN=%d) to WAL: %v\n", role(a.isPrimary), lsn, err)
+		}
+		log.Printf("%s: LSN %d was aborted by the primary, logged a no-op for it\n", role(a.isPrimary), lsn)
+	}
+}
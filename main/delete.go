@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// deleteKey removes key from store and leaves a tombstone recording the LSN that deleted it.
+// Tombstones are retained until the next snapshot compacts them. Caller must hold Mu.
+func (a *Actor) deleteKey(key string, lsn int64) {
+	if val, exists := a.store[key]; exists {
+		a.merkle.remove(key, val, a.versions[key])
+		delete(a.store, key)
+		delete(a.versions, key)
+	}
+	a.tombstones[key] = lsn
+	if a.historyRetention > 0 {
+		a.history.record(key, lsn, "", true)
+	}
+}
+
+// compactTombstones drops the tombstones a snapshot at lsn covers; the snapshot itself
+// records a deleted key by leaving it out. Caller must hold the store's lock.
+func compactTombstones(tombstones map[string]int64, lsn int64) {
+	for key, deleted := range tombstones {
+		if deleted <= lsn {
+			delete(tombstones, key)
+		}
+	}
+}
+
+// keyNotFound is the failed read of a key missing from the store, naming the delete that removed
+// it while its tombstone is retained. Caller must hold the store's lock.
+func keyNotFound(key string, tombstones map[string]int64) *Response {
+	resp := &Response{Success: false, Key: key, Error: "Key not found"}
+	if lsn, deleted := tombstones[key]; deleted {
+		resp.Error = fmt.Sprintf("Key deleted at LSN %d", lsn)
+	}
+	return resp
+}
+
+// writeType is the Request type of a replicated Write, where an empty type is a plain WRITE
+func writeType(t string) string {
+	if t == "" {
+		return "WRITE"
+	}
+	return t
+}
+
+// handleDelete processes DELETE requests, which replicate like writes and remove the key on every node
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, key string) {
+	log.Printf("Handling DELETE request for key: %s", key)
+
+	if key == "" {
+		s.sendError(w, "Key is required", http.StatusBadRequest)
+		return
+	}
+
+	expect, err := parseExpect(r)
+	if err != nil {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &Request{Type: "DELETE", Key: key, Expect: expect}
+
+	// Only primary can accept deletes, backups pass them on
+	if !s.replicator.IsPrimary() {
+		s.handleBackupWrite(w, r, req)
+		return
+	}
+
+	s.sendResponse(w, s.submitWrite(req, r.Header.Get(durabilityHeader)))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Durability selects how many replicas must log an entry before the primary commits it
+type Durability string
+
+const (
+	DurabilityAsync  Durability = "async"  // Commit once the primary has logged it
+	DurabilityQuorum Durability = "quorum" // Commit once a majority (including the primary) has logged it
+	DurabilityAll    Durability = "all"    // Commit once every replica has logged it
+
+	durabilityHeader = "X-Durability"
+)
+
+// ParseDurability validates a mode from the -durability flag or the X-Durability header
+func ParseDurability(mode string) (Durability, error) {
+	switch Durability(mode) {
+	case DurabilityAsync, DurabilityQuorum, DurabilityAll:
+		return Durability(mode), nil
+	}
+	return "", fmt.Errorf("unknown durability mode %q (use async, quorum or all)", mode)
+}
+
+// requiredAcks is the number of acks, counting the primary's own, needed out of replicas
+func (d Durability) requiredAcks(replicas int) int {
+	switch d {
+	case DurabilityAsync:
+		return 1
+	case DurabilityAll:
+		return replicas
+	case DurabilityChain:
+		return min(2, replicas) // The primary and the tail
+	default:
+		return quorumSize(replicas)
+	}
+}
+
+// requestDurability picks the request's mode from its X-Durability header, defaulting to the cluster's
+func (s *Server) requestDurability(r *http.Request) (Durability, error) {
+	return s.replicator.Durability(r.Header.Get(durabilityHeader))
+}
+
+// localAck is a local message telling the primary its own log write for an LSN is durable,
+// which alone satisfies async requests
+type localAck struct {
+	lsn int64
+}
+
+// handleLocalAck commits an LSN that needs no replica acks
+func (a *Actor) handleLocalAck(msg *localAck) {
+	if !a.isPrimary {
+		return
+	}
+	if reached, exists := a.Server.DurabilityReached(msg.lsn, a.subscribers+1); exists && reached {
+		a.commitLSN(msg.lsn)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+const (
+	electionTimeoutMin = 300 * time.Millisecond
+	electionTimeoutMax = 600 * time.Millisecond
+)
+
+// electionTimeout is a local message sent to self when a candidate's election round expires
+type electionTimeout struct {
+	term int64
+}
+
+// quorumSize returns the number of replicas (primary included) needed for a strict majority, so any
+// two quorums of the same cluster overlap even when its size is even
+func quorumSize(clusterSize int) int {
+	return clusterSize/2 + 1
+}
+
+// broadcastMembership tells every backup about the other replicas so they can run an election
+func (a *Actor) broadcastMembership(ctx actor.Context) {
+	membership := &messages.Membership{
+		Term:        a.term.Load(),
+		ClusterSize: int32(a.subscribers + 1),
+		Peers:       membershipPeers(a.targets),
+		PrimaryHttp: a.httpAddress(),
+		Chain:       a.chainPeers(),
+	}
+	membership.Shard, membership.Shards = shardsToWire(a.Server.shards.Load())
+	for _, target := range a.targets {
+		ctx.Request(target, membership)
+	}
+}
+
+// handleMembership records the other backups (excluding self) as election peers
+func (a *Actor) handleMembership(ctx actor.Context, msg *messages.Membership) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	a.peers = a.peers[:0]
+	member := false
+	for _, peer := range msg.Peers {
+		pid := actor.NewPID(peer.Address, peer.Id)
+		if pid.Equal(a.self) {
+			member = true
+			continue
+		}
+		a.peers = append(a.peers, pid)
+	}
+	if !member {
+		// Removed by a config change: stop following the primary and never stand for election
+		log.Printf("%s: Removed from the cluster by %s (term=%d)\n", role(a.isPrimary), ctx.Sender().String(), msg.Term)
+		if a.primaryPID != nil {
+			ctx.Unwatch(a.primaryPID)
+		}
+		a.peers = nil
+		a.clusterSize = 0
+		a.primaryPID = nil
+		return
+	}
+	a.clusterSize = int(msg.ClusterSize)
+	a.primaryHTTP = msg.PrimaryHttp
+	a.learnChain(msg.Chain)
+	a.learnShards(msg.Shard, msg.Shards)
+	log.Printf("%s: Membership updated (term=%d, clusterSize=%d, peers=%v)\n",
+		role(a.isPrimary), msg.Term, a.clusterSize, a.peers)
+}
+
+// handleTerminated starts an election when a backup loses its primary
+func (a *Actor) handleTerminated(ctx actor.Context, msg *actor.Terminated) {
+	if a.isPrimary || a.primaryPID == nil || !msg.Who.Equal(a.primaryPID) {
+		return
+	}
+	log.Printf("%s: Lost primary %s (reason=%s)\n", role(a.isPrimary), msg.Who.String(), msg.Why.String())
+	a.primaryAlive = false
+	a.startElection(ctx)
+}
+
+// startElection moves to the next term, votes for itself and asks every peer for a vote
+func (a *Actor) startElection(ctx actor.Context) {
+	if a.clusterSize == 0 {
+		log.Printf("%s: Cannot start election before receiving cluster membership\n", role(a.isPrimary))
+		return
+	}
+	if wait := a.leaseRemaining(); wait > 0 {
+		// The old primary may still be serving lease reads; retry once our promise to it expires
+		term := a.term.Load()
+		a.candidate = true
+		log.Printf("%s: Delaying election by %s until the primary's lease expires\n", role(a.isPrimary), wait)
+		self := a.self
+		time.AfterFunc(wait, func() {
+			a.system.Root.Send(self, &electionTimeout{term: term})
+		})
+		return
+	}
+	term := a.term.Load() + 1
+	a.term.Store(term)
+	a.votedTerm = term
+	a.persistTerm() // Before any RequestVote leaves, so a restart can't vote twice in this term
+	a.votes = 1     // Vote for self
+	a.candidate = true
+	lastApplied := a.lastAppliedLSN.Load()
+	a.electionLog = make(map[int64]*Request)
+	a.mergeElectionLog(a.unappliedEntries(lastApplied))
+	lastLogged := a.lastLoggedLSN()
+	log.Printf("%s: Starting election for term %d (lastAppliedLSN=%d, lastLoggedLSN=%d)\n",
+		role(a.isPrimary), term, lastApplied, lastLogged)
+
+	if a.votes >= quorumSize(a.clusterSize) {
+		a.becomePrimary(ctx)
+		return
+	}
+
+	for _, peer := range a.peers {
+		ctx.Request(peer, &messages.RequestVote{Term: term, LastLsn: lastLogged, AppliedLsn: lastApplied})
+	}
+	a.armElectionTimer(term)
+}
+
+// armElectionTimer sends an electionTimeout for term after a randomized delay, so competing
+// candidates don't keep splitting the vote
+func (a *Actor) armElectionTimer(term int64) {
+	timeout := electionTimeoutMin + time.Duration(rand.Int63n(int64(electionTimeoutMax-electionTimeoutMin)))
+	self := a.self
+	time.AfterFunc(timeout, func() {
+		a.system.Root.Send(self, &electionTimeout{term: term})
+	})
+}
+
+// awaitPrimary keeps a node without a live primary a candidate in term, so it starts an election
+// of its own if no primary announces itself before the timeout
+func (a *Actor) awaitPrimary(term int64) {
+	a.candidate = true
+	a.armElectionTimer(term)
+}
+
+// handleRequestVote grants at most one vote per term, and only to candidates at least as up to date.
+// A granted vote carries every entry logged here past the candidate's applied LSN, so the
+// candidate can re-replicate writes that reached a quorum but were never committed to it.
+func (a *Actor) handleRequestVote(ctx actor.Context, msg *messages.RequestVote) {
+	if msg.Term > a.term.Load() {
+		if a.isPrimary {
+			a.stepDown(ctx, msg.Term, nil)
+		} else {
+			a.setTerm(msg.Term)
+			a.candidate = false
+			if !a.primaryAlive {
+				a.awaitPrimary(msg.Term) // In case the candidate that sent this never wins
+			}
+		}
+	}
+
+	granted := !a.isPrimary &&
+		!a.primaryAlive &&
+		msg.Term == a.term.Load() &&
+		a.votedTerm < msg.Term &&
+		a.candidateUpToDate(msg.AppliedLsn, msg.LastLsn) &&
+		a.leaseRemaining() <= 0 // Never undercut a lease promised to the current primary
+	vote := &messages.Vote{Term: a.term.Load(), Granted: granted}
+	if granted {
+		a.votedTerm = msg.Term
+		a.persistTerm()
+		for _, req := range a.unappliedEntries(msg.AppliedLsn) {
+			vote.Entries = append(vote.Entries, logEntry(req))
+		}
+	}
+
+	log.Printf("%s: RequestVote(term=%d, appliedLSN=%d, lastLSN=%d) from %s, granted=%t\n",
+		role(a.isPrimary), msg.Term, msg.AppliedLsn, msg.LastLsn, ctx.Sender().String(), granted)
+	ctx.Request(ctx.Sender(), vote)
+}
+
+// candidateUpToDate compares a candidate's applied and last logged LSNs with this node's. Applied
+// LSNs come first: this node can only hand a candidate the entries it logged past the candidate's
+// applied LSN, not ones it already applied. The last logged LSN breaks ties.
+func (a *Actor) candidateUpToDate(appliedLSN, lastLogged int64) bool {
+	applied := a.lastAppliedLSN.Load()
+	if appliedLSN != applied {
+		return appliedLSN > applied
+	}
+	return lastLogged >= a.lastLoggedLSN()
+}
+
+// lastLoggedLSN is the highest LSN in Log, or lastAppliedLSN if nothing past it is logged
+func (a *Actor) lastLoggedLSN() int64 {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	last := a.lastAppliedLSN.Load()
+	for lsn := range a.Log {
+		last = max(last, lsn)
+	}
+	return last
+}
+
+// unappliedEntries lists the logged entries after lsn in LSN order
+func (a *Actor) unappliedEntries(lsn int64) []*Request {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	entries := make([]*Request, 0)
+	for entry, req := range a.Log {
+		if entry > lsn {
+			entries = append(entries, req)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LSN < entries[j].LSN })
+	return entries
+}
+
+// mergeElectionLog adds entries reported by a voter to the candidate's view of the log. Per LSN
+// the entry of the newest term wins: an older one was never committed, or the newer primary
+// would have re-replicated it. Within a term a no-op wins, since the primary only logs one
+// when it aborts the entry it first sent for that LSN.
+func (a *Actor) mergeElectionLog(entries []*Request) {
+	for _, req := range entries {
+		current, exists := a.electionLog[req.LSN]
+		if !exists || req.Term > current.Term || (req.Term == current.Term && req.Type == "NOOP") {
+			a.electionLog[req.LSN] = req
+		}
+	}
+}
+
+// handleVote counts votes for the current term and promotes on quorum
+func (a *Actor) handleVote(ctx actor.Context, msg *messages.Vote) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) {
+		a.candidate = false
+		return
+	}
+	if !a.candidate || msg.Term != a.term.Load() || !msg.Granted {
+		return
+	}
+
+	entries := make([]*Request, 0, len(msg.Entries))
+	for _, entry := range msg.Entries {
+		entries = append(entries, requestFromEntry(entry))
+	}
+	a.mergeElectionLog(entries)
+	a.votes++
+	log.Printf("%s: Received vote for term %d (%d/%d)\n",
+		role(a.isPrimary), msg.Term, a.votes, quorumSize(a.clusterSize))
+	if a.votes >= quorumSize(a.clusterSize) {
+		a.becomePrimary(ctx)
+	}
+}
+
+// handleElectionTimeout retries the election if this term produced no primary
+func (a *Actor) handleElectionTimeout(ctx actor.Context, msg *electionTimeout) {
+	if a.isPrimary || a.primaryAlive || !a.candidate || msg.term != a.term.Load() {
+		return
+	}
+	log.Printf("%s: Election for term %d timed out\n", role(a.isPrimary), msg.term)
+	a.startElection(ctx)
+}
+
+// becomePrimary promotes this backup. Every entry past its lastAppliedLSN that it or a voter
+// logged is taken over into the new term and replicated again, as it may have been committed.
+func (a *Actor) becomePrimary(ctx actor.Context) {
+	lastApplied := a.lastAppliedLSN.Load()
+	recovered := a.recoverEntries(lastApplied)
+
+	a.Mu.Lock()
+	a.isPrimary = true
+	a.candidate = false
+	a.primaryPID = nil
+	a.subscribers = a.clusterSize - 1
+	a.targets = append([]*actor.PID{}, a.peers...)
+	a.targetNames = make(map[string]string)
+	for i, target := range a.targets {
+		a.targetNames[target.String()] = fmt.Sprintf("Backup%d", i+1)
+	}
+	a.Mu.Unlock()
+
+	highest := lastApplied + int64(len(recovered))
+	a.lsn.Store(highest)
+	a.recoveredLSN = highest
+	a.resetLease()
+	a.learnChain(nil)
+	log.Printf("%s: Elected primary for term %d, re-replicating LSNs %d-%d with targets %v\n",
+		role(a.isPrimary), a.term.Load(), lastApplied+1, highest, a.targets)
+
+	a.Server.AnnounceShardPrimary(a.httpAddress(), a.term.Load())
+	for _, target := range a.targets {
+		ctx.Request(target, &messages.NewPrimary{Term: a.term.Load(), Lsn: highest, PrimaryHttp: a.httpAddress()})
+	}
+	a.replicateRecovered(ctx, recovered)
+}
+
+// recoverEntries turns the merged election log after lastApplied into this term's entries and
+// logs them. It stops at the first LSN nobody logged: LSNs commit in order and every committed
+// one reached a quorum, which shares a voter with this one, so nothing past that LSN committed.
+func (a *Actor) recoverEntries(lastApplied int64) []*Request {
+	term := a.term.Load()
+	recovered := make([]*Request, 0, len(a.electionLog))
+	for lsn := lastApplied + 1; ; lsn++ {
+		chosen, exists := a.electionLog[lsn]
+		if !exists {
+			break
+		}
+		entry := *chosen
+		entry.Term = term
+		recovered = append(recovered, &entry)
+	}
+	a.electionLog = nil
+
+	a.Mu.Lock()
+	for _, req := range recovered {
+		a.Log[req.LSN] = req
+	}
+	a.Mu.Unlock()
+	for _, req := range recovered {
+		if err := a.wal.AppendEntry(req); err != nil {
+			log.Printf("%s: Failed to log recovered LSN %d to WAL: %v\n", role(a.isPrimary), req.LSN, err)
+		}
+	}
+
+	a.pendingMu.Lock()
+	a.pendingCommits = make(map[int64]*Request) // Committed entries queued here are among the recovered ones
+	a.pendingMu.Unlock()
+	return recovered
+}
+
+// replicateRecovered sends the recovered entries to the backups. No-ops are decided like an Abort;
+// the others commit on a quorum of acks like a new write, but no client waits for them and they
+// never abort.
+func (a *Actor) replicateRecovered(ctx actor.Context, recovered []*Request) {
+	term := a.term.Load()
+	// Whatever mode the original requests asked for, a quorum is what makes them survive a failover
+	durability := DurabilityQuorum
+	if a.chainMode() {
+		durability = DurabilityChain
+	}
+	for _, req := range recovered {
+		if req.Type == "NOOP" {
+			abort := &messages.Abort{Lsn: req.LSN, Term: term}
+			a.recordAborted(req.LSN)
+			a.trackAccept(req.LSN, abort)
+			for _, target := range a.liveTargets() {
+				ctx.Request(target, abort)
+			}
+			a.pendingMu.Lock()
+			a.pendingCommits[req.LSN] = req
+			a.pendingMu.Unlock()
+			continue
+		}
+
+		if req.Type == "CONFIG" {
+			a.configLSN = req.LSN // Later changes wait until this one is applied
+		}
+		a.Server.RegisterRecoveredRequest(req.LSN, req, durability)
+		accept := acceptMessage(req, term)
+		a.trackAccept(req.LSN, accept)
+		for _, target := range a.chainTargets() {
+			ctx.Request(target, accept)
+		}
+	}
+	a.applyPendingLSNs()
+}
+
+// replicateUnapplied re-replicates the entries a restarted primary logged but never applied, as
+// a new primary does with the ones it recovers: they may have committed before the restart.
+// Slots it never logged are filled with no-ops so the entries after them can still apply.
+func (a *Actor) replicateUnapplied(ctx actor.Context) {
+	lastApplied := a.lastAppliedLSN.Load()
+	highest := a.lsn.Load()
+	if highest <= lastApplied {
+		return
+	}
+
+	unapplied := make([]*Request, 0, highest-lastApplied)
+	a.Mu.Lock()
+	for lsn := lastApplied + 1; lsn <= highest; lsn++ {
+		req, exists := a.Log[lsn]
+		if !exists {
+			req = &Request{Type: "NOOP", LSN: lsn, Term: a.term.Load()}
+			a.Log[lsn] = req
+		}
+		unapplied = append(unapplied, req)
+	}
+	a.Mu.Unlock()
+	for _, req := range unapplied {
+		if req.Type == "NOOP" {
+			if err := a.wal.AppendEntry(req); err != nil {
+				log.Printf("Primary: Failed to log no-op for LSN %d to WAL: %v\n", req.LSN, err)
+			}
+		}
+	}
+
+	a.recoveredLSN = highest
+	log.Printf("Primary: Re-replicating unapplied LSNs %d-%d after restart\n", lastApplied+1, highest)
+	a.replicateRecovered(ctx, unapplied)
+}
+
+// handleNewPrimary follows the elected primary and re-subscribes to it
+func (a *Actor) handleNewPrimary(ctx actor.Context, msg *messages.NewPrimary) {
+	if msg.Term < a.term.Load() {
+		log.Printf("%s: Rejecting NewPrimary from %s with stale term %d (current term %d)\n",
+			role(a.isPrimary), ctx.Sender().String(), msg.Term, a.term.Load())
+		ctx.Request(ctx.Sender(), a.staleTerm())
+		return
+	}
+	log.Printf("%s: New primary %s elected for term %d (primary LSN=%d)\n",
+		role(a.isPrimary), ctx.Sender().String(), msg.Term, msg.Lsn)
+	a.followPrimary(ctx, ctx.Sender(), msg.Term)
+	a.primaryHTTP = msg.PrimaryHttp
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// How a backup handles a client write, selected with -backupwrites
+const (
+	backupWritesForward  = "forward"  // Pass it to the primary over the actor system and relay the reply
+	backupWritesRedirect = "redirect" // Reply 307 Temporary Redirect to the primary's HTTP address
+)
+
+// forwardWriteTimeout bounds how long a backup waits on the primary for a forwarded write. It outlasts
+// the primary's own requestTimeout, so a slow write is relayed as the primary's 408 rather than
+// failing here first, when the client couldn't tell whether it committed.
+const forwardWriteTimeout = requestTimeout + 5*time.Second
+
+// ParseBackupWrites validates the -backupwrites flag
+func ParseBackupWrites(mode string) (string, error) {
+	switch mode {
+	case backupWritesForward, backupWritesRedirect:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown backup write mode %q (use forward or redirect)", mode)
+}
+
+// writeForwarder is implemented by engines that can hand a backup's client write to the primary
+type writeForwarder interface {
+	ForwardWrite(msg *messages.ForwardWrite) (*messages.ForwardWriteReply, error)
+}
+
+// SetBackupWrites selects how this node handles writes while it is a backup
+func (s *Server) SetBackupWrites(mode string) {
+	s.backupWrites = mode
+}
+
+// handleBackupWrite forwards a client write to the primary, or redirects the client there,
+// so clients can send writes to any node
+func (s *Server) handleBackupWrite(w http.ResponseWriter, r *http.Request, req *Request) {
+	forwarder, ok := s.replicator.(writeForwarder)
+	if s.backupWrites == backupWritesRedirect || !ok {
+		s.redirectWrite(w, r)
+		return
+	}
+
+	reply, err := forwarder.ForwardWrite(&messages.ForwardWrite{
+		Type:       req.Type,
+		Key:        req.Key,
+		Val:        req.Val,
+		Durability: r.Header.Get(durabilityHeader),
+		Expect:     req.Expect,
+		Txn:        txnToWire(req.Ops),
+	})
+	if err != nil {
+		log.Printf("Backup: Failed to forward %s for key %s: %v", req.Type, req.Key, err)
+		s.sendError(w, fmt.Sprintf("Failed to forward write to the primary: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	s.sendResponse(w, &Response{
+		Success:    reply.Success,
+		Key:        reply.Key,
+		Value:      reply.Value,
+		Error:      reply.Error,
+		Status:     int(reply.Status),
+		Durability: Durability(reply.Durability),
+		LSN:        reply.Lsn,
+		Version:    reply.Version,
+	})
+}
+
+// redirectWrite sends the client to the same POST, DELETE or transaction on the primary
+func (s *Server) redirectWrite(w http.ResponseWriter, r *http.Request) {
+	primary := s.replicator.PrimaryHTTP()
+	if primary == "" {
+		s.sendError(w, "Only primary can accept writes, and the primary is unknown", http.StatusServiceUnavailable)
+		return
+	}
+	target := url.URL{Scheme: "http", Host: primary, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	log.Printf("Backup: Redirecting write to %s", target.String())
+	http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+}
+
+// answerForwardedWrite runs a write a backup forwarded as if a client had sent it here, and
+// replies to the backup once it commits. It never forwards again, so a stale primary can't loop.
+func (s *Server) answerForwardedWrite(system *actor.ActorSystem, sender *actor.PID, msg *messages.ForwardWrite) {
+	if !s.replicator.IsPrimary() {
+		system.Root.Send(sender, &messages.ForwardWriteReply{
+			Key:    msg.Key,
+			Error:  "Forwarded to a node that is no longer primary",
+			Status: http.StatusServiceUnavailable,
+		})
+		return
+	}
+
+	go func() {
+		req := &Request{Type: writeType(msg.Type), Key: msg.Key, Val: msg.Val, Expect: msg.Expect, Ops: txnFromWire(msg.Txn)}
+		resp := s.submitWrite(req, msg.Durability)
+		system.Root.Send(sender, &messages.ForwardWriteReply{
+			Success:    resp.Success,
+			Key:        resp.Key,
+			Value:      resp.Value,
+			Error:      resp.Error,
+			Status:     int32(resp.Status),
+			Lsn:        resp.LSN,
+			Durability: string(resp.Durability),
+			Version:    resp.Version,
+		})
+	}()
+}
+
+// forwardWrite sends msg to primary and waits for the committed reply
+func forwardWrite(system *actor.ActorSystem, primary *actor.PID, msg *messages.ForwardWrite) (*messages.ForwardWriteReply, error) {
+	if primary == nil {
+		return nil, fmt.Errorf("no primary to forward to")
+	}
+	result, err := system.Root.RequestFuture(primary, msg, forwardWriteTimeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("forward to %s: %w", primary.Address, err)
+	}
+	reply, ok := result.(*messages.ForwardWriteReply)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply %T from %s", result, primary.Address)
+	}
+	return reply, nil
+}
+
+// ForwardWrite hands a client write to the primary this backup follows
+func (a *Actor) ForwardWrite(msg *messages.ForwardWrite) (*messages.ForwardWriteReply, error) {
+	return forwardWrite(a.system, a.primaryPID, msg)
+}
+
+// ForwardWrite hands a client write to the leader this follower knows of
+func (n *RaftNode) ForwardWrite(msg *messages.ForwardWrite) (*messages.ForwardWriteReply, error) {
+	n.mu.Lock()
+	leader := n.leader
+	n.mu.Unlock()
+	return forwardWrite(n.system, leader, msg)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Health is a replica's state as seen by the failure detector
+type Health string
+
+const (
+	HealthAlive   Health = "alive"
+	HealthSuspect Health = "suspect" // Missed heartbeats but still gets replication traffic
+	HealthDead    Health = "dead"    // Dropped from the fan-out until it answers a heartbeat again
+)
+
+// failureDetector is a timeout detector over heartbeat traffic: a node is suspect once it has been
+// silent for suspectAfter and dead once silent for deadAfter
+type failureDetector struct {
+	mu           sync.Mutex
+	suspectAfter time.Duration
+	deadAfter    time.Duration
+	lastHeard    map[string]time.Time
+	health       map[string]Health
+}
+
+func newFailureDetector(suspectAfter, deadAfter time.Duration) *failureDetector {
+	return &failureDetector{
+		suspectAfter: suspectAfter,
+		deadAfter:    deadAfter,
+		lastHeard:    make(map[string]time.Time),
+		health:       make(map[string]Health),
+	}
+}
+
+// heard records traffic from node, reporting whether it had been declared dead
+func (d *failureDetector) heard(node string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	revived := d.health[node] == HealthDead
+	d.lastHeard[node] = time.Now()
+	d.health[node] = HealthAlive
+	return revived
+}
+
+// check re-evaluates node and reports whether its state changed. A node never heard from
+// gets a full deadAfter from its first check, and a node is always suspect for one check before dead.
+func (d *failureDetector) check(node string) (Health, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last, known := d.lastHeard[node]
+	if !known {
+		d.lastHeard[node] = time.Now()
+		d.health[node] = HealthAlive
+		return HealthAlive, false
+	}
+
+	state := HealthAlive
+	switch silence := time.Since(last); {
+	case d.deadAfter > 0 && silence >= d.deadAfter && d.health[node] != HealthAlive:
+		state = HealthDead
+	case d.suspectAfter > 0 && silence >= d.suspectAfter:
+		// Never straight from alive to dead: that means this node itself stalled past deadAfter,
+		// so the heartbeats waiting in its mailbox get one more interval to arrive
+		state = HealthSuspect
+	}
+	changed := d.health[node] != state
+	d.health[node] = state
+	return state, changed
+}
+
+// dead reports whether node was declared dead at its last check
+func (d *failureDetector) dead(node string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.health[node] == HealthDead
+}
+
+// forget drops node, e.g. once it is no longer a member
+func (d *failureDetector) forget(node string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.lastHeard, node)
+	delete(d.health, node)
+}
+
+// status reports node's state and how long ago it was last heard from
+func (d *failureDetector) status(node string) (Health, time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last, known := d.lastHeard[node]
+	if !known {
+		return HealthAlive, 0
+	}
+	return d.health[node], time.Since(last)
+}
+
+// liveTargets are the replicas the primary fans replication messages out to: every member
+// the failure detector hasn't declared dead
+func (a *Actor) liveTargets() []*actor.PID {
+	live := make([]*actor.PID, 0, len(a.targets))
+	for _, target := range a.targets {
+		if !a.detector.dead(target.String()) {
+			live = append(live, target)
+		}
+	}
+	return live
+}
+
+// checkReplicaHealth runs on every heartbeat tick of the primary and logs state changes
+func (a *Actor) checkReplicaHealth(ctx actor.Context) {
+	for _, target := range a.targets {
+		state, changed := a.detector.check(target.String())
+		if !changed {
+			continue
+		}
+		log.Printf("Primary: Replica %s is now %s\n", target.String(), state)
+		if state == HealthDead {
+			log.Printf("Primary: Removed %s from the fan-out; still counted for quorum, heartbeats continue\n", target.String())
+			if a.chainMode() {
+				a.broadcastMembership(ctx) // Relink the chain around it
+			}
+		}
+	}
+}
+
+// replicaHeard notes traffic from a replica on the primary. A replica coming back from dead
+// missed every message sent while it was out, so it gets a state transfer before rejoining the fan-out.
+func (a *Actor) replicaHeard(ctx actor.Context, appliedLSN int64) {
+	replica := ctx.Sender()
+	if !a.detector.heard(replica.String()) {
+		return
+	}
+	log.Printf("Primary: Replica %s is alive again (appliedLSN=%d), catching it up\n", replica.String(), appliedLSN)
+	a.sendStateTransfer(ctx, replica, appliedLSN)
+	if a.chainMode() {
+		a.broadcastMembership(ctx) // Put it back in the chain
+	}
+}
+
+// checkPrimaryHealth runs on every heartbeat tick of a backup and starts an election once the
+// followed primary has gone silent for deadAfter, even if the connection to it never dropped
+func (a *Actor) checkPrimaryHealth(ctx actor.Context) {
+	if a.isPrimary || a.primaryPID == nil || !a.primaryAlive || a.clusterSize == 0 {
+		return
+	}
+	state, changed := a.detector.check(a.primaryPID.String())
+	if !changed {
+		return
+	}
+	log.Printf("%s: Primary %s is now %s\n", role(a.isPrimary), a.primaryPID.String(), state)
+	if state == HealthDead {
+		a.primaryAlive = false
+		a.startElection(ctx)
+	}
+}
+
+// NodeHealth is one replica's entry in GET /admin/health
+type NodeHealth struct {
+	Address     string `json:"address"`
+	Role        string `json:"role"`
+	State       Health `json:"state"`
+	LastHeardMs int64  `json:"last_heard_ms"`
+}
+
+// HealthStatus is the JSON body returned by GET /admin/health
+type HealthStatus struct {
+	Node     string        `json:"node"`
+	Role     string        `json:"role"`
+	Term     int64         `json:"term"`
+	Replicas []*NodeHealth `json:"replicas"`
+}
+
+// HealthStatus reports what this node's failure detector thinks of the nodes it watches:
+// every backup on the primary, the followed primary on a backup
+func (a *Actor) HealthStatus() *HealthStatus {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	status := &HealthStatus{Node: a.self.Address, Role: role(a.isPrimary), Term: a.term.Load(), Replicas: []*NodeHealth{}}
+	report := func(pid *actor.PID, role string) {
+		state, silence := a.detector.status(pid.String())
+		status.Replicas = append(status.Replicas, &NodeHealth{
+			Address:     pid.Address,
+			Role:        role,
+			State:       state,
+			LastHeardMs: silence.Milliseconds(),
+		})
+	}
+	if a.isPrimary {
+		for _, target := range a.targets {
+			report(target, "Backup")
+		}
+	} else if a.primaryPID != nil {
+		report(a.primaryPID, "Primary")
+	}
+	return status
+}
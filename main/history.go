@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// keyVersion is a value a key took at the LSN that wrote it, or its deletion at that LSN
+type keyVersion struct {
+	lsn     int64
+	val     string
+	deleted bool
+}
+
+// storeHistory keeps the versions of every key, oldest first, so the store can be read as of
+// any LSN from floor on. store stays the index of latest values; the history sits beside it.
+type storeHistory struct {
+	keys  map[string][]keyVersion
+	floor int64 // Oldest LSN the history can answer for
+}
+
+// newStoreHistory starts a history from store as of floor, where versions holds each key's write LSN
+func newStoreHistory(store map[string]string, versions map[string]int64, floor int64) *storeHistory {
+	h := &storeHistory{keys: make(map[string][]keyVersion, len(store)), floor: floor}
+	for key, val := range store {
+		h.keys[key] = []keyVersion{{lsn: versions[key], val: val}}
+	}
+	return h
+}
+
+// record adds the version of key written (or deleted) at lsn. Versions at or after lsn are
+// dropped first, which only happens when a repair overwrites a key out of LSN order.
+func (h *storeHistory) record(key string, lsn int64, val string, deleted bool) {
+	versions := h.keys[key]
+	for len(versions) > 0 && versions[len(versions)-1].lsn >= lsn {
+		versions = versions[:len(versions)-1]
+	}
+	h.keys[key] = append(versions, keyVersion{lsn: lsn, val: val, deleted: deleted})
+}
+
+// at is the version of key as of lsn, false if it didn't exist then
+func (h *storeHistory) at(key string, lsn int64) (keyVersion, bool) {
+	versions := h.keys[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].lsn <= lsn {
+			return versions[i], !versions[i].deleted
+		}
+	}
+	return keyVersion{}, false
+}
+
+// collect drops every version no read at horizon or later can see: all but the newest one at or
+// before horizon, and that one too if it is a deletion
+func (h *storeHistory) collect(horizon int64) int {
+	if horizon <= h.floor {
+		return 0
+	}
+	dropped := 0
+	for key, versions := range h.keys {
+		keep := 0
+		for i := range versions {
+			if versions[i].lsn <= horizon {
+				keep = i
+			}
+		}
+		if versions[keep].deleted && versions[keep].lsn <= horizon {
+			keep++
+		}
+		if keep == 0 {
+			continue
+		}
+		dropped += keep
+		if keep == len(versions) {
+			delete(h.keys, key)
+		} else {
+			h.keys[key] = append([]keyVersion(nil), versions[keep:]...)
+		}
+	}
+	h.floor = horizon
+	return dropped
+}
+
+// read answers a read of key as of lsn, for a node keeping retention LSNs of history
+func (h *storeHistory) read(key string, lsn, retention int64) *Response {
+	if retention <= 0 {
+		return &Response{Success: false, Key: key, Error: "Historical reads are disabled (-history=0)", Status: http.StatusNotImplemented}
+	}
+	if lsn < h.floor {
+		return &Response{
+			Success: false,
+			Key:     key,
+			Error:   fmt.Sprintf("LSN %d is older than the retained history, which starts at LSN %d", lsn, h.floor),
+			Status:  http.StatusGone,
+		}
+	}
+	version, exists := h.at(key, lsn)
+	if !exists {
+		return &Response{Success: false, Key: key, Error: fmt.Sprintf("Key not found at LSN %d", lsn)}
+	}
+	return &Response{Success: true, Key: key, Value: version.val, LSN: lsn, Version: version.lsn}
+}
+
+// historyReader is implemented by engines that support GET /key?at_lsn=N
+type historyReader interface {
+	ReadAt(key string, lsn int64) *Response
+}
+
+// handleReadAt serves GET /key?at_lsn=N from this node's history once it has applied N. A backup
+// that doesn't get there within minLSNWait redirects the client to the primary.
+func (s *Server) handleReadAt(w http.ResponseWriter, r *http.Request, key, param string) {
+	lsn, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || lsn < 0 {
+		s.sendError(w, "at_lsn must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	reader, ok := s.replicator.(historyReader)
+	if !ok {
+		s.sendError(w, "Historical reads are not supported by this replicator", http.StatusNotImplemented)
+		return
+	}
+	if !s.waitForLSN(lsn, s.minLSNWait) {
+		s.redirectToPrimary(w, r, key, fmt.Sprintf("Not caught up to at_lsn %d (lastAppliedLSN=%d)", lsn, s.replicator.AppliedLSN()))
+		return
+	}
+	s.sendResponse(w, reader.ReadAt(key, lsn))
+}
+
+// maybeCollectHistory prunes the history once historyRetention LSNs were applied since the last
+// time, so it holds at most about twice the retention window of versions besides one per key
+func (a *Actor) maybeCollectHistory() {
+	applied := a.lastAppliedLSN.Load()
+	if a.historyRetention <= 0 || applied-a.historyCollected < a.historyRetention {
+		return
+	}
+	a.Mu.Lock()
+	a.historyCollected = applied
+	dropped := a.history.collect(applied - a.historyRetention)
+	floor := a.history.floor
+	a.Mu.Unlock()
+	log.Printf("%s: Pruned %d versions from the history, which now starts at LSN %d\n", role(a.isPrimary), dropped, floor)
+}
+
+// ReadAt reads key from the store as it was at lsn, which this node has applied
+func (a *Actor) ReadAt(key string, lsn int64) *Response {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+	return a.history.read(key, lsn, a.historyRetention)
+}
+
+// collectHistory prunes the history like maybeCollectHistory. Caller must hold mu.
+func (n *RaftNode) collectHistory() {
+	applied := n.lastApplied.Load()
+	if n.historyRetention <= 0 || applied-n.historyCollected < n.historyRetention {
+		return
+	}
+	n.historyCollected = applied
+	dropped := n.history.collect(applied - n.historyRetention)
+	log.Printf("Raft: Pruned %d versions from the history, which now starts at index %d\n", dropped, n.history.floor)
+}
+
+// ReadAt reads key from the store as it was at log index lsn, which this node has applied
+func (n *RaftNode) ReadAt(key string, lsn int64) *Response {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.history.read(key, lsn, n.historyRetention)
+}
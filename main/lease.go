@@ -0,0 +1,165 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// readLease is reported as the durability of reads served from the primary's store under its lease
+const readLease Durability = "lease"
+
+// processStart anchors monotonic timestamps echoed in heartbeats, so wall clock jumps can't extend a lease
+var processStart = time.Now()
+
+func monotonicNow() int64 {
+	return int64(time.Since(processStart))
+}
+
+// heartbeatTick is a local message that makes the primary send a heartbeat round
+type heartbeatTick struct{}
+
+// startHeartbeatTimer ticks every heartbeatInterval, but often enough that a healthy primary
+// renews its lease well before it expires
+func (a *Actor) startHeartbeatTimer() {
+	interval := a.heartbeatInterval
+	if a.leaseDuration > 0 && (interval <= 0 || a.leaseDuration/4 < interval) {
+		interval = a.leaseDuration / 4
+	}
+	if interval <= 0 {
+		return
+	}
+	self := a.self
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.system.Root.Send(self, &heartbeatTick{})
+		}
+	}()
+}
+
+// handleHeartbeatTick sends a Heartbeat stamped with the primary's clock to every backup,
+// dead ones included so they can come back, and runs the failure detector
+func (a *Actor) handleHeartbeatTick(ctx actor.Context) {
+	if !a.isPrimary {
+		a.checkPrimaryHealth(ctx)
+		return
+	}
+	a.checkReplicaHealth(ctx)
+	commitLSN := a.commitIndex()
+	heartbeat := &messages.Heartbeat{Term: a.term.Load(), SentAt: monotonicNow(), CommitLsn: commitLSN, Aborted: a.abortedThrough(commitLSN)}
+	for _, target := range a.targets {
+		ctx.Request(target, heartbeat)
+	}
+}
+
+// handleHeartbeat promises the primary not to vote for anyone else for leaseDuration and echoes its timestamp
+func (a *Actor) handleHeartbeat(ctx actor.Context, msg *messages.Heartbeat) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) || a.isPrimary {
+		return
+	}
+	a.lastHeartbeat = time.Now()
+	if a.detector.heard(ctx.Sender().String()) && ctx.Sender().Equal(a.primaryPID) && !a.primaryAlive {
+		// Declared dead too early; it is still primary for this term, so stop the pending election
+		log.Printf("%s: Primary %s is alive again\n", role(a.isPrimary), ctx.Sender().String())
+		a.primaryAlive = true
+		a.candidate = false
+	}
+	a.advanceCommitIndex(ctx, msg.CommitLsn, msg.Aborted)
+	ctx.Request(ctx.Sender(), &messages.HeartbeatAck{Term: a.term.Load(), SentAt: msg.SentAt, AppliedLsn: a.lastAppliedLSN.Load()})
+}
+
+// handleHeartbeatAck extends the lease to cover the heartbeat round the replica acked
+func (a *Actor) handleHeartbeatAck(ctx actor.Context, msg *messages.HeartbeatAck) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) || !a.isPrimary || msg.Term != a.term.Load() {
+		return
+	}
+	replica := ctx.Sender().String()
+	if _, member := a.targetNames[replica]; !member {
+		return
+	}
+
+	a.leaseMu.Lock()
+	a.leaseAcks[replica] = max(a.leaseAcks[replica], msg.SentAt)
+	a.leaseMu.Unlock()
+	a.replicaHeard(ctx, msg.AppliedLsn)
+	a.recordReplicaApplied(replica, msg.AppliedLsn)
+
+	a.confirmReadIndexes(ctx)
+}
+
+// leaseValid reports whether a quorum of replicas acked a heartbeat recent enough that none of them
+// can have voted for a new primary yet. The lease runs from when the heartbeat was sent, which is never
+// later than when a backup received it, minus a margin for clock rate drift.
+func (a *Actor) leaseValid() bool {
+	if a.leaseDuration <= 0 || !a.isPrimary {
+		return false
+	}
+	if a.lastAppliedLSN.Load() < a.recoveredLSN {
+		return false // Recovered entries may hold writes the last primary already acknowledged
+	}
+	needed := quorumSize(a.subscribers+1) - 1 // The primary's own vote is implicit
+	if needed <= 0 {
+		return true
+	}
+
+	a.leaseMu.Lock()
+	acks := make([]int64, 0, len(a.targets))
+	for _, target := range a.targets {
+		if sentAt, acked := a.leaseAcks[target.String()]; acked {
+			acks = append(acks, sentAt)
+		}
+	}
+	a.leaseMu.Unlock()
+	if len(acks) < needed {
+		return false
+	}
+
+	slices.Sort(acks)
+	slices.Reverse(acks)
+	expiry := acks[needed-1] + int64(a.leaseDuration-a.leaseDuration/10)
+	return monotonicNow() < expiry
+}
+
+// resetLease drops every heartbeat ack so a new term starts without a lease
+func (a *Actor) resetLease() {
+	a.leaseMu.Lock()
+	a.leaseAcks = make(map[string]int64)
+	a.leaseMu.Unlock()
+}
+
+// leaseRemaining is how long this backup must still honour its promise to the last primary it heard from
+func (a *Actor) leaseRemaining() time.Duration {
+	if a.leaseDuration <= 0 || a.lastHeartbeat.IsZero() {
+		return 0
+	}
+	return a.leaseDuration - time.Since(a.lastHeartbeat)
+}
+
+// readUnderLease answers a primary read from store without allocating an LSN
+func (a *Actor) readUnderLease(req *Request) {
+	a.Mu.Lock()
+	val, exists := a.store[req.Key]
+	version := a.versions[req.Key]
+	notFound := keyNotFound(req.Key, a.tombstones)
+	a.Mu.Unlock()
+
+	log.Printf("Primary: Lease read Key=%s (found=%t)\n", req.Key, exists)
+	if !exists {
+		a.Server.CompletePendingRequest(req.LSN, notFound)
+		return
+	}
+	a.Server.CompletePendingRequest(req.LSN, &Response{
+		Success:    true,
+		Key:        req.Key,
+		Value:      val,
+		Durability: readLease,
+		LSN:        a.lastAppliedLSN.Load(),
+		Version:    version,
+	})
+}
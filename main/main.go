@@ -9,6 +9,8 @@ import (
 	"log"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/asynkron/protoactor-go/actor"
@@ -42,9 +44,72 @@ func main() {
 	httpPort := flag.Int("http", 8081, "Port for HTTP server")
 	isPrimary := flag.Bool("primary", false, "Run as primary node")
 	backups := flag.Int("backups", 2, "Number of backup nodes (only for primary)")
+	dataDir := flag.String("datadir", "data", "Directory for the write-ahead log (a subdirectory per actor port)")
+	fsync := flag.String("fsync", string(FsyncAlways), "WAL fsync policy: always, interval or never")
+	fsyncInterval := flag.Duration("fsyncinterval", 10*time.Millisecond, "How often the WAL is synced with -fsync=interval")
+	walSegmentSize := flag.Int64("walsegment", 16<<20, "WAL segment size in bytes before rotating")
+	snapshotEvery := flag.Int64("snapshotevery", 10000, "Applied LSNs between snapshots and log truncation (0 disables)")
+	retransmit := flag.Duration("retransmit", 500*time.Millisecond, "Initial delay before resending unacked replication messages (0 disables)")
+	abortAfter := flag.Duration("abortafter", 5*time.Second, "Abort an LSN that hasn't reached quorum within this deadline (0 disables; ignored with -replication=chain)")
+	durability := flag.String("durability", string(DurabilityQuorum), "Default replication durability: async, quorum or all (overridable per request with X-Durability)")
+	lease := flag.Duration("lease", 2*time.Second, "Primary read lease renewed by replica heartbeats; reads fall back to the log when it lapses (0 disables)")
+	minLSNWait := flag.Duration("minlsnwait", time.Second, "How long a backup GET with min_lsn waits to catch up before redirecting to the primary")
+	batchSize := flag.Int("batchsize", 64, "Max entries the primary coalesces into one WriteBatch (1 disables batching)")
+	batchLinger := flag.Duration("batchlinger", time.Millisecond, "How long the primary waits for more entries before sending a partial batch")
+	heartbeat := flag.Duration("heartbeat", 500*time.Millisecond, "How often the primary heartbeats its backups (capped at -lease/4; 0 disables unless -lease is set)")
+	suspectAfter := flag.Duration("suspectafter", 1500*time.Millisecond, "Silence after which the failure detector marks a node suspect")
+	replicationMode := flag.String("replication", replicationPrimary, "Replication scheme: primary (fan-out, quorum acks) or chain (head to tail, the tail acks and serves strongly consistent reads)")
+	deadAfter := flag.Duration("deadafter", 4*time.Second, "Silence after which a backup is dropped from the fan-out, or a backup starts an election against its primary")
+	replicatorEngine := flag.String("replicator", replicatorPrimaryBackup, "Replication engine: primary-backup or raft")
+	raftPeers := flag.String("peers", "", "Comma-separated actor host:port of the other Raft nodes (only with -replicator=raft)")
+	shardList := flag.String("shards", "", "Shard map partitioning keys across replica groups, as comma-separated id=primary-http-host:port (primaries only; backups learn it)")
+	shardID := flag.String("shard", "", "This replica group's id in -shards")
+	antiEntropy := flag.Duration("antientropy", 5*time.Second, "How often the primary compares Merkle trees of the store with each backup and repairs divergent keys (0 disables)")
+	historyRetention := flag.Int64("history", 1000, "Applied LSNs of per-key history kept for GET /key?at_lsn=N reads at a past LSN (0 disables)")
+	backupWritesMode := flag.String("backupwrites", backupWritesForward, "How a backup handles client writes: forward (relay to the primary over the actor system) or redirect (307 to the primary)")
 
 	flag.Parse()
 
+	defaultDurability, err := ParseDurability(*durability)
+	if err != nil {
+		log.Fatalf("Invalid -durability: %v", err)
+	}
+	replication, err := ParseReplication(*replicationMode)
+	if err != nil {
+		log.Fatalf("Invalid -replication: %v", err)
+	}
+	if replication == replicationChain {
+		defaultDurability = DurabilityChain
+	}
+	engine, err := ParseReplicator(*replicatorEngine)
+	if err != nil {
+		log.Fatalf("Invalid -replicator: %v", err)
+	}
+	backupWrites, err := ParseBackupWrites(*backupWritesMode)
+	if err != nil {
+		log.Fatalf("Invalid -backupwrites: %v", err)
+	}
+	var shardMap *ShardMap
+	if *shardList != "" || *shardID != "" {
+		if shardMap, err = ParseShardMap(*shardList, *shardID); err != nil {
+			log.Fatalf("Invalid -shards: %v", err)
+		}
+	}
+	if engine == replicatorRaft {
+		runRaft(*port, *httpPort, *dataDir, *raftPeers, *minLSNWait, *historyRetention, *snapshotEvery, shardMap, backupWrites)
+		return
+	}
+
+	wal, walState, err := OpenWAL(WALOptions{
+		Dir:           filepath.Join(*dataDir, strconv.Itoa(*port)),
+		SegmentSize:   *walSegmentSize,
+		Fsync:         FsyncPolicy(*fsync),
+		FsyncInterval: *fsyncInterval,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open WAL: %v", err)
+	}
+
 	selfIP := getLocalIP()
 	// selfIP := "127.0.0.1"
 	system := actor.NewActorSystem()
@@ -56,19 +121,38 @@ func main() {
 		log.Println("Starting as Primary")
 		props := actor.PropsFromProducer(func() actor.Actor {
 			actor := &Actor{
-				targets:        []*actor.PID{},
-				targetNames:    make(map[string]string),
-				system:         system,
-				remoter:        remoter,
-				subscribers:    *backups,
-				isPrimary:      *isPrimary,
-				Log:            make(map[int64]*Request),
-				store:          make(map[string]string),
-				httpPort:       *httpPort,
-				pendingCommits: make(map[int64]*Request), // Initialize pending commits queue
+				targets:             []*actor.PID{},
+				targetNames:         make(map[string]string),
+				system:              system,
+				remoter:             remoter,
+				subscribers:         *backups,
+				isPrimary:           *isPrimary,
+				Log:                 make(map[int64]*Request),
+				store:               make(map[string]string),
+				httpPort:            *httpPort,
+				pendingCommits:      make(map[int64]*Request), // Initialize pending commits queue
+				wal:                 wal,
+				snapshotEvery:       *snapshotEvery,
+				retransmitInterval:  *retransmit,
+				retransmits:         make(map[int64]*retransmitEntry),
+				nackedAt:            make(map[int64]time.Time),
+				abortAfter:          *abortAfter,
+				durability:          defaultDurability,
+				leaseDuration:       *lease,
+				minLSNWait:          *minLSNWait,
+				batchSize:           *batchSize,
+				batchLinger:         *batchLinger,
+				heartbeatInterval:   *heartbeat,
+				detector:            newFailureDetector(*suspectAfter, *deadAfter),
+				replication:         replication,
+				antiEntropyInterval: *antiEntropy,
+				historyRetention:    *historyRetention,
 				// firstRun:       true,
 			}
-			actor.Server = NewServer(actor, *httpPort)
+			actor.restoreFromWAL(walState)
+			actor.Server = NewServer(actor, *httpPort, *minLSNWait)
+			actor.Server.SetShardMap(shardMap)
+			actor.Server.SetBackupWrites(backupWrites)
 			return actor
 		})
 		remoter.Register("primary", props)
@@ -91,16 +175,37 @@ func main() {
 
 		props := actor.PropsFromProducer(func() actor.Actor {
 			actor := &Actor{
-				targets:        []*actor.PID{actor.NewPID(primaryIP, "primary")},
-				isPrimary:      *isPrimary,
-				Log:            make(map[int64]*Request),
-				store:          make(map[string]string),
-				httpPort:       *httpPort,
-				serverStarted:  false,
-				pendingCommits: make(map[int64]*Request), // Initialize pending commits queue
+				targets:             []*actor.PID{actor.NewPID(primaryIP, "primary")},
+				targetNames:         make(map[string]string),
+				system:              system,
+				remoter:             remoter,
+				isPrimary:           *isPrimary,
+				Log:                 make(map[int64]*Request),
+				store:               make(map[string]string),
+				httpPort:            *httpPort,
+				serverStarted:       false,
+				pendingCommits:      make(map[int64]*Request), // Initialize pending commits queue
+				wal:                 wal,
+				snapshotEvery:       *snapshotEvery,
+				retransmitInterval:  *retransmit,
+				retransmits:         make(map[int64]*retransmitEntry),
+				nackedAt:            make(map[int64]time.Time),
+				abortAfter:          *abortAfter,
+				durability:          defaultDurability,
+				leaseDuration:       *lease,
+				minLSNWait:          *minLSNWait,
+				batchSize:           *batchSize,
+				batchLinger:         *batchLinger,
+				heartbeatInterval:   *heartbeat,
+				detector:            newFailureDetector(*suspectAfter, *deadAfter),
+				replication:         replication,
+				antiEntropyInterval: *antiEntropy,
+				historyRetention:    *historyRetention,
 				// firstRun:       true,
 			}
-			actor.Server = NewServer(actor, *httpPort)
+			actor.restoreFromWAL(walState)
+			actor.Server = NewServer(actor, *httpPort, *minLSNWait)
+			actor.Server.SetBackupWrites(backupWrites)
 			return actor
 		})
 		remoter.Register("backup", props)
@@ -128,3 +233,35 @@ func main() {
 	// system.Root.Stop(pid)
 	// remoter.Shutdown(true)
 }
+
+// runRaft starts a Raft node. Every node runs the same actor; -primary and -backups don't apply,
+// since the leader is elected and the cluster is the fixed set of -peers plus this node.
+func runRaft(port, httpPort int, dataDir, peerList string, minLSNWait time.Duration, historyRetention, snapshotEvery int64, shardMap *ShardMap, backupWrites string) {
+	peers := parseRaftPeers(peerList)
+	if len(peers) == 0 {
+		log.Fatalf("-replicator=raft needs -peers")
+	}
+	storage, recovered, err := openRaftStorage(filepath.Join(dataDir, strconv.Itoa(port), "raft"))
+	if err != nil {
+		log.Fatalf("Failed to open Raft log: %v", err)
+	}
+
+	log.Printf("Starting as Raft node with %d peers", len(peers))
+	system := actor.NewActorSystem()
+	remoter := remote.NewRemote(system, remote.Configure(getLocalIP(), port))
+	props := actor.PropsFromProducer(func() actor.Actor {
+		node := NewRaftNode(system, peers, httpPort, minLSNWait, historyRetention, snapshotEvery, storage, recovered)
+		node.Server.SetShardMap(shardMap)
+		node.Server.SetBackupWrites(backupWrites)
+		return node
+	})
+	remoter.Register(raftActorName, props)
+	remoter.Start()
+
+	pid, err := system.Root.SpawnNamed(props, raftActorName)
+	if err != nil {
+		log.Fatalf("Failed to spawn Raft actor: %v", err)
+	}
+	log.Printf("Spawned Raft actor with PID: %v", pid)
+	select {}
+}
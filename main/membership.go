@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// Config changes are logged as a CONFIG entry: Key holds the operation, Val the backup addresses after it
+const (
+	configAdd    = "add"
+	configRemove = "remove"
+)
+
+// memberAddresses lists the current backups' actor addresses. Caller must hold Mu.
+func (a *Actor) memberAddresses() []string {
+	addresses := make([]string, 0, len(a.targets))
+	for _, target := range a.targets {
+		addresses = append(addresses, target.Address)
+	}
+	return addresses
+}
+
+// ChangeMembership logs a single-step config change adding or removing one backup. Only one change
+// may be in flight and it commits under the old config, so since a majority of n and a majority of
+// n±1 together exceed the larger cluster, the old and new quorums always overlap; it takes effect
+// once committed.
+func (a *Actor) ChangeMembership(req *Request, op, address string) {
+	tempLSN := req.LSN
+	fail := func(message string, status int) {
+		a.Server.CompletePendingRequest(tempLSN, &Response{Success: false, Key: req.Key, Error: message, Status: status})
+	}
+
+	a.Mu.Lock()
+	if a.configLSN > a.lastAppliedLSN.Load() {
+		a.Mu.Unlock()
+		fail(fmt.Sprintf("Config change at LSN %d is still in progress", a.configLSN), http.StatusConflict)
+		return
+	}
+	members := a.memberAddresses()
+	switch {
+	case op == configAdd && slices.Contains(members, address):
+		a.Mu.Unlock()
+		fail(fmt.Sprintf("%s is already a member", address), http.StatusBadRequest)
+		return
+	case op == configAdd && address == a.self.Address:
+		a.Mu.Unlock()
+		fail("Cannot add the primary as a backup", http.StatusBadRequest)
+		return
+	case op == configRemove && address == a.self.Address:
+		a.Mu.Unlock()
+		fail("Cannot remove the primary", http.StatusBadRequest)
+		return
+	case op == configRemove && !slices.Contains(members, address):
+		a.Mu.Unlock()
+		fail(fmt.Sprintf("%s is not a member", address), http.StatusNotFound)
+		return
+	}
+	if op == configAdd {
+		members = append(members, address)
+	} else {
+		members = slices.DeleteFunc(members, func(member string) bool { return member == address })
+	}
+
+	req.Key = op + " " + address
+	req.Val = strings.Join(members, ",")
+	req.LSN = a.lsn.Add(1)
+	req.Term = a.term.Load()
+	a.configLSN = req.LSN
+	a.Log[req.LSN] = req
+	a.Mu.Unlock()
+
+	a.Server.UpdatePendingRequestLSN(tempLSN, req.LSN, req)
+	if !a.logToWAL(req) {
+		return
+	}
+
+	accept := configChange(req, a.term.Load())
+	log.Printf("Primary: Proposing config change %q at LSN %d (members=%v)\n", req.Key, req.LSN, members)
+	a.trackAccept(req.LSN, accept)
+	for _, target := range a.liveTargets() {
+		a.ctx.Request(target, accept)
+	}
+}
+
+// configChange builds the replication message for a logged CONFIG entry
+func configChange(req *Request, term int64) *messages.ConfigChange {
+	msg := &messages.ConfigChange{Lsn: req.LSN, Term: term, Op: req.Key}
+	for _, address := range configMembers(req) {
+		msg.Members = append(msg.Members, &messages.Peer{Address: address, Id: "backup"})
+	}
+	return msg
+}
+
+// configMembers decodes the backup addresses stored in a CONFIG entry
+func configMembers(req *Request) []string {
+	if req.Val == "" {
+		return nil
+	}
+	return strings.Split(req.Val, ",")
+}
+
+// handleConfigChange logs a config change on a backup and acks it like a Write;
+// the new membership reaches backups through the Membership broadcast once committed
+func (a *Actor) handleConfigChange(ctx actor.Context, msg *messages.ConfigChange) {
+	log.Printf("%s: Received ConfigChange(LSN=%d, Op=%s) from %s\n", role(a.isPrimary), msg.Lsn, msg.Op, ctx.Sender().String())
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	a.observeLSN(msg.Lsn)
+
+	addresses := make([]string, 0, len(msg.Members))
+	for _, member := range msg.Members {
+		addresses = append(addresses, member.Address)
+	}
+	req := &Request{Type: "CONFIG", Key: msg.Op, Val: strings.Join(addresses, ","), LSN: msg.Lsn, Term: msg.Term}
+	if msg.Lsn > a.lastAppliedLSN.Load() && !a.isAborted(msg.Lsn, msg.Term) {
+		a.Mu.Lock()
+		a.Log[msg.Lsn] = req
+		a.Mu.Unlock()
+		if err := a.wal.AppendEntry(req); err != nil {
+			log.Printf("%s: Failed to log ConfigChange(LSN=%d) to WAL, not acking: %v\n", role(a.isPrimary), msg.Lsn, err)
+			return
+		}
+	}
+	ctx.Request(ctx.Sender(), &messages.Ack{Lsn: msg.Lsn, Term: a.term.Load()})
+}
+
+// applyConfig switches the primary to the committed member list and tells every replica,
+// including removed ones, about it. Caller must hold Mu.
+func (a *Actor) applyConfig(ctx actor.Context, req *Request) {
+	a.config = req
+	removed := a.adoptMembers(configMembers(req))
+
+	log.Printf("Primary: Applied config change %q at LSN %d, quorum is now %d of %d\n",
+		req.Key, req.LSN, quorumSize(a.clusterSize), a.clusterSize)
+	a.broadcastMembership(ctx)
+
+	// Removed replicas get the new membership too so they stop following and never start an election
+	for _, pid := range removed {
+		delete(a.targetNames, pid.String())
+		a.detector.forget(pid.String())
+		ctx.Request(pid, &messages.Membership{Term: a.term.Load(), ClusterSize: int32(a.clusterSize), Peers: membershipPeers(a.targets)})
+	}
+}
+
+// adoptMembers makes members the primary's backups, keeping the PIDs it already has for them,
+// and returns the backups that are no longer members. Caller must hold Mu.
+func (a *Actor) adoptMembers(members []string) map[string]*actor.PID {
+	current := make(map[string]*actor.PID, len(a.targets))
+	for _, target := range a.targets {
+		current[target.Address] = target
+	}
+
+	targets := make([]*actor.PID, 0, len(members))
+	for _, address := range members {
+		target, exists := current[address]
+		if !exists {
+			target = actor.NewPID(address, "backup")
+			a.targetNames[target.String()] = fmt.Sprintf("Backup%d", len(a.targetNames)+1)
+		}
+		delete(current, address)
+		targets = append(targets, target)
+	}
+	a.targets = targets
+	a.subscribers = len(targets)
+	a.clusterSize = len(targets) + 1
+	return current
+}
+
+// membershipPeers converts PIDs into their wire form
+func membershipPeers(pids []*actor.PID) []*messages.Peer {
+	peers := make([]*messages.Peer, 0, len(pids))
+	for _, pid := range pids {
+		peers = append(peers, &messages.Peer{Address: pid.Address, Id: pid.Id})
+	}
+	return peers
+}
+
+// MemberStatus is the JSON body returned by GET /admin/members
+type MemberStatus struct {
+	Primary     string   `json:"primary"`
+	Term        int64    `json:"term"`
+	ClusterSize int      `json:"cluster_size"`
+	Quorum      int      `json:"quorum"`
+	Members     []string `json:"members"`
+}
+
+// MemberStatus reports this node's view of the cluster configuration
+func (a *Actor) MemberStatus() *MemberStatus {
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	status := &MemberStatus{Term: a.term.Load(), ClusterSize: a.clusterSize, Quorum: quorumSize(a.clusterSize)}
+	if a.isPrimary {
+		status.Primary = a.self.Address
+		status.Members = a.memberAddresses()
+	} else {
+		if a.primaryPID != nil {
+			status.Primary = a.primaryPID.Address
+		}
+		status.Members = []string{a.self.Address}
+		for _, peer := range a.peers {
+			status.Members = append(status.Members, peer.Address)
+		}
+	}
+	return status
+}
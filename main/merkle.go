@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// The Merkle tree over store has a root, merkleFanout inner nodes and merkleFanout^2 leaf buckets
+const (
+	merkleFanout = 16
+	merkleLeaves = merkleFanout * merkleFanout
+	merkleDepth  = 2 // Level of the leaves; the root is level 0
+)
+
+// merkleTree hashes store into leaf buckets. A leaf is the XOR of the hashes of the keys in its
+// bucket with their values and versions (If-Match makes a version-only divergence visible), so a
+// write updates it in O(1) without rescanning the bucket; inner nodes are hashed from their
+// children on demand. The value is copied to freeze it at an LSN.
+type merkleTree struct {
+	leaves [merkleLeaves]uint64
+}
+
+// merkleBucket is the leaf a key hashes into
+func merkleBucket(key string) int {
+	return int(hashKey(key) % merkleLeaves)
+}
+
+func merkleEntryHash(key, val string, version int64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(val))
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(version))
+	h.Write(buf)
+	return h.Sum64()
+}
+
+// set records that key changed from old at oldVersion (if it existed) to val at version
+func (t *merkleTree) set(key, old string, oldVersion int64, existed bool, val string, version int64) {
+	bucket := merkleBucket(key)
+	if existed {
+		t.leaves[bucket] ^= merkleEntryHash(key, old, oldVersion)
+	}
+	t.leaves[bucket] ^= merkleEntryHash(key, val, version)
+}
+
+// remove records that key, holding val at version, was deleted
+func (t *merkleTree) remove(key, val string, version int64) {
+	t.leaves[merkleBucket(key)] ^= merkleEntryHash(key, val, version)
+}
+
+// rebuild rehashes every leaf from store and versions, after they were replaced wholesale
+func (t *merkleTree) rebuild(store map[string]string, versions map[string]int64) {
+	t.leaves = [merkleLeaves]uint64{}
+	for key, val := range store {
+		t.leaves[merkleBucket(key)] ^= merkleEntryHash(key, val, versions[key])
+	}
+}
+
+// node is the hash of the index'th node at level
+func (t *merkleTree) node(level, index int) uint64 {
+	if level == merkleDepth {
+		return t.leaves[index]
+	}
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, child := range merkleChildren(level, index) {
+		binary.BigEndian.PutUint64(buf, t.node(level+1, child))
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+func (t *merkleTree) root() uint64 {
+	return t.node(0, 0)
+}
+
+// merkleChildren lists the indexes at level+1 under the index'th node at level
+func merkleChildren(level, index int) []int {
+	children := make([]int, 0, merkleFanout)
+	if level < 0 || level >= merkleDepth || index < 0 || index >= merkleLevelWidth(level) {
+		return children
+	}
+	for i := 0; i < merkleFanout; i++ {
+		children = append(children, index*merkleFanout+i)
+	}
+	return children
+}
+
+// merkleLevelWidth is the number of nodes at level
+func merkleLevelWidth(level int) int {
+	width := 1
+	for i := 0; i < level; i++ {
+		width *= merkleFanout
+	}
+	return width
+}
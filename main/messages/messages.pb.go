@@ -27,6 +27,12 @@ type Write struct {
 	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
 	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
 	Val           string                 `protobuf:"bytes,4,opt,name=val,proto3" json:"val,omitempty"`
+	Term          int64                  `protobuf:"varint,5,opt,name=term,proto3" json:"term,omitempty"`
+	CommitLsn     int64                  `protobuf:"varint,6,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	Aborted       []int64                `protobuf:"varint,7,rep,packed,name=aborted,proto3" json:"aborted,omitempty"`
+	Type          string                 `protobuf:"bytes,8,opt,name=type,proto3" json:"type,omitempty"`
+	Expect        int64                  `protobuf:"varint,9,opt,name=expect,proto3" json:"expect,omitempty"`
+	Txn           *Transaction           `protobuf:"bytes,10,opt,name=txn,proto3" json:"txn,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,11 +95,56 @@ func (x *Write) GetVal() string {
 	return ""
 }
 
+func (x *Write) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Write) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *Write) GetAborted() []int64 {
+	if x != nil {
+		return x.Aborted
+	}
+	return nil
+}
+
+func (x *Write) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Write) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+func (x *Write) GetTxn() *Transaction {
+	if x != nil {
+		return x.Txn
+	}
+	return nil
+}
+
 type Read struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
 	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
 	Request       string                 `protobuf:"bytes,3,opt,name=request,proto3" json:"request,omitempty"`
+	Term          int64                  `protobuf:"varint,4,opt,name=term,proto3" json:"term,omitempty"`
+	CommitLsn     int64                  `protobuf:"varint,5,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	Aborted       []int64                `protobuf:"varint,6,rep,packed,name=aborted,proto3" json:"aborted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -149,10 +200,37 @@ func (x *Read) GetRequest() string {
 	return ""
 }
 
+func (x *Read) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Read) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *Read) GetAborted() []int64 {
+	if x != nil {
+		return x.Aborted
+	}
+	return nil
+}
+
 type Ack struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
 	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Commit        bool                   `protobuf:"varint,3,opt,name=commit,proto3" json:"commit,omitempty"`
+	Term          int64                  `protobuf:"varint,4,opt,name=term,proto3" json:"term,omitempty"`
+	Batch         bool                   `protobuf:"varint,5,opt,name=batch,proto3" json:"batch,omitempty"`
+	AppliedLsn    int64                  `protobuf:"varint,6,opt,name=applied_lsn,json=appliedLsn,proto3" json:"applied_lsn,omitempty"`
+	Chain         bool                   `protobuf:"varint,7,opt,name=chain,proto3" json:"chain,omitempty"`
+	Abort         bool                   `protobuf:"varint,8,opt,name=abort,proto3" json:"abort,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -201,10 +279,53 @@ func (x *Ack) GetLsn() int64 {
 	return 0
 }
 
+func (x *Ack) GetCommit() bool {
+	if x != nil {
+		return x.Commit
+	}
+	return false
+}
+
+func (x *Ack) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Ack) GetBatch() bool {
+	if x != nil {
+		return x.Batch
+	}
+	return false
+}
+
+func (x *Ack) GetAppliedLsn() int64 {
+	if x != nil {
+		return x.AppliedLsn
+	}
+	return 0
+}
+
+func (x *Ack) GetChain() bool {
+	if x != nil {
+		return x.Chain
+	}
+	return false
+}
+
+func (x *Ack) GetAbort() bool {
+	if x != nil {
+		return x.Abort
+	}
+	return false
+}
+
 type Commit struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
 	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Term          int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -253,9 +374,18 @@ func (x *Commit) GetLsn() int64 {
 	return 0
 }
 
+func (x *Commit) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
 type Subscribe struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	LastLsn       int64                  `protobuf:"varint,2,opt,name=last_lsn,json=lastLsn,proto3" json:"last_lsn,omitempty"`
+	Term          int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -297,28 +427,2853 @@ func (x *Subscribe) GetSenderIp() string {
 	return ""
 }
 
-var File_messages_proto protoreflect.FileDescriptor
+func (x *Subscribe) GetLastLsn() int64 {
+	if x != nil {
+		return x.LastLsn
+	}
+	return 0
+}
 
-const file_messages_proto_rawDesc = "" +
-	"\n" +
-	"\x0emessages.proto\x12\bmessages\"Z\n" +
-	"\x05Write\x12\x1b\n" +
-	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
-	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x10\n" +
-	"\x03key\x18\x03 \x01(\tR\x03key\x12\x10\n" +
-	"\x03val\x18\x04 \x01(\tR\x03val\"O\n" +
-	"\x04Read\x12\x1b\n" +
-	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
-	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x18\n" +
-	"\arequest\x18\x03 \x01(\tR\arequest\"4\n" +
-	"\x03Ack\x12\x1b\n" +
-	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
-	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\"7\n" +
-	"\x06Commit\x12\x1b\n" +
-	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
-	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\"(\n" +
-	"\tSubscribe\x12\x1b\n" +
-	"\tsender_ip\x18\x01 \x01(\tR\bsenderIpB\fZ\n" +
+func (x *Subscribe) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type Peer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_messages_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Peer) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Peer) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Membership struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	ClusterSize   int32                  `protobuf:"varint,3,opt,name=cluster_size,json=clusterSize,proto3" json:"cluster_size,omitempty"`
+	Peers         []*Peer                `protobuf:"bytes,4,rep,name=peers,proto3" json:"peers,omitempty"`
+	PrimaryHttp   string                 `protobuf:"bytes,5,opt,name=primary_http,json=primaryHttp,proto3" json:"primary_http,omitempty"`
+	Chain         []*Peer                `protobuf:"bytes,6,rep,name=chain,proto3" json:"chain,omitempty"`
+	Shard         string                 `protobuf:"bytes,7,opt,name=shard,proto3" json:"shard,omitempty"`
+	Shards        []*Shard               `protobuf:"bytes,8,rep,name=shards,proto3" json:"shards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Membership) Reset() {
+	*x = Membership{}
+	mi := &file_messages_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Membership) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Membership) ProtoMessage() {}
+
+func (x *Membership) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Membership.ProtoReflect.Descriptor instead.
+func (*Membership) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Membership) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Membership) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Membership) GetClusterSize() int32 {
+	if x != nil {
+		return x.ClusterSize
+	}
+	return 0
+}
+
+func (x *Membership) GetPeers() []*Peer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *Membership) GetPrimaryHttp() string {
+	if x != nil {
+		return x.PrimaryHttp
+	}
+	return ""
+}
+
+func (x *Membership) GetChain() []*Peer {
+	if x != nil {
+		return x.Chain
+	}
+	return nil
+}
+
+func (x *Membership) GetShard() string {
+	if x != nil {
+		return x.Shard
+	}
+	return ""
+}
+
+func (x *Membership) GetShards() []*Shard {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type Shard struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PrimaryHttp   string                 `protobuf:"bytes,2,opt,name=primary_http,json=primaryHttp,proto3" json:"primary_http,omitempty"`
+	Term          int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shard) Reset() {
+	*x = Shard{}
+	mi := &file_messages_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shard) ProtoMessage() {}
+
+func (x *Shard) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shard.ProtoReflect.Descriptor instead.
+func (*Shard) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Shard) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Shard) GetPrimaryHttp() string {
+	if x != nil {
+		return x.PrimaryHttp
+	}
+	return ""
+}
+
+func (x *Shard) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type RequestVote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	LastLsn       int64                  `protobuf:"varint,3,opt,name=last_lsn,json=lastLsn,proto3" json:"last_lsn,omitempty"`
+	AppliedLsn    int64                  `protobuf:"varint,4,opt,name=applied_lsn,json=appliedLsn,proto3" json:"applied_lsn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestVote) Reset() {
+	*x = RequestVote{}
+	mi := &file_messages_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestVote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestVote) ProtoMessage() {}
+
+func (x *RequestVote) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestVote.ProtoReflect.Descriptor instead.
+func (*RequestVote) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RequestVote) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *RequestVote) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RequestVote) GetLastLsn() int64 {
+	if x != nil {
+		return x.LastLsn
+	}
+	return 0
+}
+
+func (x *RequestVote) GetAppliedLsn() int64 {
+	if x != nil {
+		return x.AppliedLsn
+	}
+	return 0
+}
+
+type Vote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Granted       bool                   `protobuf:"varint,3,opt,name=granted,proto3" json:"granted,omitempty"`
+	Entries       []*LogEntry            `protobuf:"bytes,4,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vote) Reset() {
+	*x = Vote{}
+	mi := &file_messages_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vote) ProtoMessage() {}
+
+func (x *Vote) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vote.ProtoReflect.Descriptor instead.
+func (*Vote) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Vote) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Vote) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Vote) GetGranted() bool {
+	if x != nil {
+		return x.Granted
+	}
+	return false
+}
+
+func (x *Vote) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type NewPrimary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn           int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	PrimaryHttp   string                 `protobuf:"bytes,4,opt,name=primary_http,json=primaryHttp,proto3" json:"primary_http,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NewPrimary) Reset() {
+	*x = NewPrimary{}
+	mi := &file_messages_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NewPrimary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NewPrimary) ProtoMessage() {}
+
+func (x *NewPrimary) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NewPrimary.ProtoReflect.Descriptor instead.
+func (*NewPrimary) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *NewPrimary) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *NewPrimary) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *NewPrimary) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *NewPrimary) GetPrimaryHttp() string {
+	if x != nil {
+		return x.PrimaryHttp
+	}
+	return ""
+}
+
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lsn           int64                  `protobuf:"varint,1,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Val           string                 `protobuf:"bytes,4,opt,name=val,proto3" json:"val,omitempty"`
+	Term          int64                  `protobuf:"varint,5,opt,name=term,proto3" json:"term,omitempty"`
+	Expect        int64                  `protobuf:"varint,6,opt,name=expect,proto3" json:"expect,omitempty"`
+	Txn           *Transaction           `protobuf:"bytes,7,opt,name=txn,proto3" json:"txn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_messages_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *LogEntry) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *LogEntry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *LogEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LogEntry) GetVal() string {
+	if x != nil {
+		return x.Val
+	}
+	return ""
+}
+
+func (x *LogEntry) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *LogEntry) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+func (x *LogEntry) GetTxn() *Transaction {
+	if x != nil {
+		return x.Txn
+	}
+	return nil
+}
+
+type StateTransfer struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp         string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term             int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	CommitLsn        int64                  `protobuf:"varint,3,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	SnapshotLsn      int64                  `protobuf:"varint,4,opt,name=snapshot_lsn,json=snapshotLsn,proto3" json:"snapshot_lsn,omitempty"`
+	Snapshot         map[string]string      `protobuf:"bytes,5,rep,name=snapshot,proto3" json:"snapshot,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Entries          []*LogEntry            `protobuf:"bytes,6,rep,name=entries,proto3" json:"entries,omitempty"`
+	SnapshotVersions map[string]int64       `protobuf:"bytes,7,rep,name=snapshot_versions,json=snapshotVersions,proto3" json:"snapshot_versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	HasSnapshot      bool                   `protobuf:"varint,8,opt,name=has_snapshot,json=hasSnapshot,proto3" json:"has_snapshot,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StateTransfer) Reset() {
+	*x = StateTransfer{}
+	mi := &file_messages_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StateTransfer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StateTransfer) ProtoMessage() {}
+
+func (x *StateTransfer) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StateTransfer.ProtoReflect.Descriptor instead.
+func (*StateTransfer) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StateTransfer) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *StateTransfer) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *StateTransfer) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *StateTransfer) GetSnapshotLsn() int64 {
+	if x != nil {
+		return x.SnapshotLsn
+	}
+	return 0
+}
+
+func (x *StateTransfer) GetSnapshot() map[string]string {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *StateTransfer) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *StateTransfer) GetSnapshotVersions() map[string]int64 {
+	if x != nil {
+		return x.SnapshotVersions
+	}
+	return nil
+}
+
+func (x *StateTransfer) GetHasSnapshot() bool {
+	if x != nil {
+		return x.HasSnapshot
+	}
+	return false
+}
+
+type WalRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	Val           string                 `protobuf:"bytes,5,opt,name=val,proto3" json:"val,omitempty"`
+	Store         map[string]string      `protobuf:"bytes,6,rep,name=store,proto3" json:"store,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Term          int64                  `protobuf:"varint,7,opt,name=term,proto3" json:"term,omitempty"`
+	Config        *WalRecord             `protobuf:"bytes,8,opt,name=config,proto3" json:"config,omitempty"`
+	Expect        int64                  `protobuf:"varint,9,opt,name=expect,proto3" json:"expect,omitempty"`
+	Versions      map[string]int64       `protobuf:"bytes,10,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Txn           *Transaction           `protobuf:"bytes,11,opt,name=txn,proto3" json:"txn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WalRecord) Reset() {
+	*x = WalRecord{}
+	mi := &file_messages_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WalRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WalRecord) ProtoMessage() {}
+
+func (x *WalRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WalRecord.ProtoReflect.Descriptor instead.
+func (*WalRecord) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WalRecord) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *WalRecord) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *WalRecord) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WalRecord) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WalRecord) GetVal() string {
+	if x != nil {
+		return x.Val
+	}
+	return ""
+}
+
+func (x *WalRecord) GetStore() map[string]string {
+	if x != nil {
+		return x.Store
+	}
+	return nil
+}
+
+func (x *WalRecord) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *WalRecord) GetConfig() *WalRecord {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *WalRecord) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+func (x *WalRecord) GetVersions() map[string]int64 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *WalRecord) GetTxn() *Transaction {
+	if x != nil {
+		return x.Txn
+	}
+	return nil
+}
+
+type Nack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	LastApplied   int64                  `protobuf:"varint,2,opt,name=last_applied,json=lastApplied,proto3" json:"last_applied,omitempty"`
+	Lsns          []int64                `protobuf:"varint,3,rep,packed,name=lsns,proto3" json:"lsns,omitempty"`
+	Term          int64                  `protobuf:"varint,4,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Nack) Reset() {
+	*x = Nack{}
+	mi := &file_messages_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Nack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Nack) ProtoMessage() {}
+
+func (x *Nack) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Nack.ProtoReflect.Descriptor instead.
+func (*Nack) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Nack) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Nack) GetLastApplied() int64 {
+	if x != nil {
+		return x.LastApplied
+	}
+	return 0
+}
+
+func (x *Nack) GetLsns() []int64 {
+	if x != nil {
+		return x.Lsns
+	}
+	return nil
+}
+
+func (x *Nack) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type Abort struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Term          int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Abort) Reset() {
+	*x = Abort{}
+	mi := &file_messages_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Abort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Abort) ProtoMessage() {}
+
+func (x *Abort) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Abort.ProtoReflect.Descriptor instead.
+func (*Abort) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Abort) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Abort) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *Abort) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type StaleTerm struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Primary       *Peer                  `protobuf:"bytes,3,opt,name=primary,proto3" json:"primary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StaleTerm) Reset() {
+	*x = StaleTerm{}
+	mi := &file_messages_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StaleTerm) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StaleTerm) ProtoMessage() {}
+
+func (x *StaleTerm) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StaleTerm.ProtoReflect.Descriptor instead.
+func (*StaleTerm) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *StaleTerm) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *StaleTerm) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *StaleTerm) GetPrimary() *Peer {
+	if x != nil {
+		return x.Primary
+	}
+	return nil
+}
+
+type ConfigChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Lsn           int64                  `protobuf:"varint,2,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Term          int64                  `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	Op            string                 `protobuf:"bytes,4,opt,name=op,proto3" json:"op,omitempty"`
+	Members       []*Peer                `protobuf:"bytes,5,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigChange) Reset() {
+	*x = ConfigChange{}
+	mi := &file_messages_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigChange) ProtoMessage() {}
+
+func (x *ConfigChange) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigChange.ProtoReflect.Descriptor instead.
+func (*ConfigChange) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ConfigChange) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ConfigChange) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *ConfigChange) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ConfigChange) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *ConfigChange) GetMembers() []*Peer {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type Heartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	SentAt        int64                  `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	CommitLsn     int64                  `protobuf:"varint,4,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	Aborted       []int64                `protobuf:"varint,5,rep,packed,name=aborted,proto3" json:"aborted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file_messages_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Heartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Heartbeat) ProtoMessage() {}
+
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Heartbeat) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Heartbeat) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetSentAt() int64 {
+	if x != nil {
+		return x.SentAt
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetAborted() []int64 {
+	if x != nil {
+		return x.Aborted
+	}
+	return nil
+}
+
+type HeartbeatAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	SentAt        int64                  `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	AppliedLsn    int64                  `protobuf:"varint,4,opt,name=applied_lsn,json=appliedLsn,proto3" json:"applied_lsn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatAck) Reset() {
+	*x = HeartbeatAck{}
+	mi := &file_messages_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatAck) ProtoMessage() {}
+
+func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatAck.ProtoReflect.Descriptor instead.
+func (*HeartbeatAck) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *HeartbeatAck) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *HeartbeatAck) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *HeartbeatAck) GetSentAt() int64 {
+	if x != nil {
+		return x.SentAt
+	}
+	return 0
+}
+
+func (x *HeartbeatAck) GetAppliedLsn() int64 {
+	if x != nil {
+		return x.AppliedLsn
+	}
+	return 0
+}
+
+type ReadIndex struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadIndex) Reset() {
+	*x = ReadIndex{}
+	mi := &file_messages_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadIndex) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadIndex) ProtoMessage() {}
+
+func (x *ReadIndex) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadIndex.ProtoReflect.Descriptor instead.
+func (*ReadIndex) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReadIndex) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ReadIndex) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+type ReadIndexReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	CommitLsn     int64                  `protobuf:"varint,3,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	Ok            bool                   `protobuf:"varint,4,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadIndexReply) Reset() {
+	*x = ReadIndexReply{}
+	mi := &file_messages_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadIndexReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadIndexReply) ProtoMessage() {}
+
+func (x *ReadIndexReply) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadIndexReply.ProtoReflect.Descriptor instead.
+func (*ReadIndexReply) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReadIndexReply) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ReadIndexReply) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ReadIndexReply) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *ReadIndexReply) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type WriteBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Entries       []*LogEntry            `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	CommitLsn     int64                  `protobuf:"varint,4,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	Aborted       []int64                `protobuf:"varint,5,rep,packed,name=aborted,proto3" json:"aborted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteBatch) Reset() {
+	*x = WriteBatch{}
+	mi := &file_messages_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteBatch) ProtoMessage() {}
+
+func (x *WriteBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteBatch.ProtoReflect.Descriptor instead.
+func (*WriteBatch) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WriteBatch) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *WriteBatch) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *WriteBatch) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *WriteBatch) GetCommitLsn() int64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *WriteBatch) GetAborted() []int64 {
+	if x != nil {
+		return x.Aborted
+	}
+	return nil
+}
+
+type RaftEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	Val           string                 `protobuf:"bytes,5,opt,name=val,proto3" json:"val,omitempty"`
+	Expect        int64                  `protobuf:"varint,6,opt,name=expect,proto3" json:"expect,omitempty"`
+	Txn           *Transaction           `protobuf:"bytes,7,opt,name=txn,proto3" json:"txn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftEntry) Reset() {
+	*x = RaftEntry{}
+	mi := &file_messages_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftEntry) ProtoMessage() {}
+
+func (x *RaftEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftEntry.ProtoReflect.Descriptor instead.
+func (*RaftEntry) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RaftEntry) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *RaftEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *RaftEntry) GetVal() string {
+	if x != nil {
+		return x.Val
+	}
+	return ""
+}
+
+func (x *RaftEntry) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetTxn() *Transaction {
+	if x != nil {
+		return x.Txn
+	}
+	return nil
+}
+
+type AppendEntries struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	PrevLogIndex  int64                  `protobuf:"varint,3,opt,name=prev_log_index,json=prevLogIndex,proto3" json:"prev_log_index,omitempty"`
+	PrevLogTerm   int64                  `protobuf:"varint,4,opt,name=prev_log_term,json=prevLogTerm,proto3" json:"prev_log_term,omitempty"`
+	Entries       []*RaftEntry           `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"`
+	LeaderCommit  int64                  `protobuf:"varint,6,opt,name=leader_commit,json=leaderCommit,proto3" json:"leader_commit,omitempty"`
+	LeaderHttp    string                 `protobuf:"bytes,7,opt,name=leader_http,json=leaderHttp,proto3" json:"leader_http,omitempty"`
+	Shards        []*Shard               `protobuf:"bytes,8,rep,name=shards,proto3" json:"shards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendEntries) Reset() {
+	*x = AppendEntries{}
+	mi := &file_messages_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendEntries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendEntries) ProtoMessage() {}
+
+func (x *AppendEntries) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendEntries.ProtoReflect.Descriptor instead.
+func (*AppendEntries) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AppendEntries) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *AppendEntries) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendEntries) GetPrevLogIndex() int64 {
+	if x != nil {
+		return x.PrevLogIndex
+	}
+	return 0
+}
+
+func (x *AppendEntries) GetPrevLogTerm() int64 {
+	if x != nil {
+		return x.PrevLogTerm
+	}
+	return 0
+}
+
+func (x *AppendEntries) GetEntries() []*RaftEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *AppendEntries) GetLeaderCommit() int64 {
+	if x != nil {
+		return x.LeaderCommit
+	}
+	return 0
+}
+
+func (x *AppendEntries) GetLeaderHttp() string {
+	if x != nil {
+		return x.LeaderHttp
+	}
+	return ""
+}
+
+func (x *AppendEntries) GetShards() []*Shard {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type InstallSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	LastIndex     int64                  `protobuf:"varint,3,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	LastTerm      int64                  `protobuf:"varint,4,opt,name=last_term,json=lastTerm,proto3" json:"last_term,omitempty"`
+	Store         map[string]string      `protobuf:"bytes,5,rep,name=store,proto3" json:"store,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	LeaderHttp    string                 `protobuf:"bytes,6,opt,name=leader_http,json=leaderHttp,proto3" json:"leader_http,omitempty"`
+	Versions      map[string]int64       `protobuf:"bytes,7,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstallSnapshot) Reset() {
+	*x = InstallSnapshot{}
+	mi := &file_messages_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstallSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstallSnapshot) ProtoMessage() {}
+
+func (x *InstallSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstallSnapshot.ProtoReflect.Descriptor instead.
+func (*InstallSnapshot) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *InstallSnapshot) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *InstallSnapshot) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *InstallSnapshot) GetLastIndex() int64 {
+	if x != nil {
+		return x.LastIndex
+	}
+	return 0
+}
+
+func (x *InstallSnapshot) GetLastTerm() int64 {
+	if x != nil {
+		return x.LastTerm
+	}
+	return 0
+}
+
+func (x *InstallSnapshot) GetStore() map[string]string {
+	if x != nil {
+		return x.Store
+	}
+	return nil
+}
+
+func (x *InstallSnapshot) GetLeaderHttp() string {
+	if x != nil {
+		return x.LeaderHttp
+	}
+	return ""
+}
+
+func (x *InstallSnapshot) GetVersions() map[string]int64 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type AppendEntriesReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Success       bool                   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	MatchIndex    int64                  `protobuf:"varint,4,opt,name=match_index,json=matchIndex,proto3" json:"match_index,omitempty"`
+	ConflictIndex int64                  `protobuf:"varint,5,opt,name=conflict_index,json=conflictIndex,proto3" json:"conflict_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendEntriesReply) Reset() {
+	*x = AppendEntriesReply{}
+	mi := &file_messages_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendEntriesReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendEntriesReply) ProtoMessage() {}
+
+func (x *AppendEntriesReply) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendEntriesReply.ProtoReflect.Descriptor instead.
+func (*AppendEntriesReply) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AppendEntriesReply) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *AppendEntriesReply) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendEntriesReply) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AppendEntriesReply) GetMatchIndex() int64 {
+	if x != nil {
+		return x.MatchIndex
+	}
+	return 0
+}
+
+func (x *AppendEntriesReply) GetConflictIndex() int64 {
+	if x != nil {
+		return x.ConflictIndex
+	}
+	return 0
+}
+
+type RaftRequestVote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	LastLogIndex  int64                  `protobuf:"varint,3,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
+	LastLogTerm   int64                  `protobuf:"varint,4,opt,name=last_log_term,json=lastLogTerm,proto3" json:"last_log_term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftRequestVote) Reset() {
+	*x = RaftRequestVote{}
+	mi := &file_messages_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftRequestVote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftRequestVote) ProtoMessage() {}
+
+func (x *RaftRequestVote) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftRequestVote.ProtoReflect.Descriptor instead.
+func (*RaftRequestVote) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RaftRequestVote) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *RaftRequestVote) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftRequestVote) GetLastLogIndex() int64 {
+	if x != nil {
+		return x.LastLogIndex
+	}
+	return 0
+}
+
+func (x *RaftRequestVote) GetLastLogTerm() int64 {
+	if x != nil {
+		return x.LastLogTerm
+	}
+	return 0
+}
+
+type RaftVote struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Granted       bool                   `protobuf:"varint,3,opt,name=granted,proto3" json:"granted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftVote) Reset() {
+	*x = RaftVote{}
+	mi := &file_messages_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftVote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftVote) ProtoMessage() {}
+
+func (x *RaftVote) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftVote.ProtoReflect.Descriptor instead.
+func (*RaftVote) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RaftVote) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *RaftVote) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftVote) GetGranted() bool {
+	if x != nil {
+		return x.Granted
+	}
+	return false
+}
+
+type ForwardWrite struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Val           string                 `protobuf:"bytes,3,opt,name=val,proto3" json:"val,omitempty"`
+	Durability    string                 `protobuf:"bytes,4,opt,name=durability,proto3" json:"durability,omitempty"`
+	Type          string                 `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Expect        int64                  `protobuf:"varint,6,opt,name=expect,proto3" json:"expect,omitempty"`
+	Txn           *Transaction           `protobuf:"bytes,7,opt,name=txn,proto3" json:"txn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForwardWrite) Reset() {
+	*x = ForwardWrite{}
+	mi := &file_messages_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForwardWrite) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardWrite) ProtoMessage() {}
+
+func (x *ForwardWrite) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardWrite.ProtoReflect.Descriptor instead.
+func (*ForwardWrite) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ForwardWrite) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ForwardWrite) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ForwardWrite) GetVal() string {
+	if x != nil {
+		return x.Val
+	}
+	return ""
+}
+
+func (x *ForwardWrite) GetDurability() string {
+	if x != nil {
+		return x.Durability
+	}
+	return ""
+}
+
+func (x *ForwardWrite) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ForwardWrite) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+func (x *ForwardWrite) GetTxn() *Transaction {
+	if x != nil {
+		return x.Txn
+	}
+	return nil
+}
+
+type ForwardWriteReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Status        int32                  `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	Lsn           int64                  `protobuf:"varint,6,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Durability    string                 `protobuf:"bytes,7,opt,name=durability,proto3" json:"durability,omitempty"`
+	Value         string                 `protobuf:"bytes,8,opt,name=value,proto3" json:"value,omitempty"`
+	Version       int64                  `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForwardWriteReply) Reset() {
+	*x = ForwardWriteReply{}
+	mi := &file_messages_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForwardWriteReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardWriteReply) ProtoMessage() {}
+
+func (x *ForwardWriteReply) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardWriteReply.ProtoReflect.Descriptor instead.
+func (*ForwardWriteReply) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ForwardWriteReply) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ForwardWriteReply) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ForwardWriteReply) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ForwardWriteReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ForwardWriteReply) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *ForwardWriteReply) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *ForwardWriteReply) GetDurability() string {
+	if x != nil {
+		return x.Durability
+	}
+	return ""
+}
+
+func (x *ForwardWriteReply) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ForwardWriteReply) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type MerkleRoot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn           int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Root          uint64                 `protobuf:"varint,4,opt,name=root,proto3" json:"root,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleRoot) Reset() {
+	*x = MerkleRoot{}
+	mi := &file_messages_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleRoot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleRoot) ProtoMessage() {}
+
+func (x *MerkleRoot) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleRoot.ProtoReflect.Descriptor instead.
+func (*MerkleRoot) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *MerkleRoot) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *MerkleRoot) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *MerkleRoot) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *MerkleRoot) GetRoot() uint64 {
+	if x != nil {
+		return x.Root
+	}
+	return 0
+}
+
+type MerkleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn           int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Level         int32                  `protobuf:"varint,4,opt,name=level,proto3" json:"level,omitempty"`
+	Indexes       []int32                `protobuf:"varint,5,rep,packed,name=indexes,proto3" json:"indexes,omitempty"`
+	AppliedLsn    int64                  `protobuf:"varint,6,opt,name=applied_lsn,json=appliedLsn,proto3" json:"applied_lsn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleRequest) Reset() {
+	*x = MerkleRequest{}
+	mi := &file_messages_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleRequest) ProtoMessage() {}
+
+func (x *MerkleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleRequest.ProtoReflect.Descriptor instead.
+func (*MerkleRequest) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *MerkleRequest) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *MerkleRequest) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *MerkleRequest) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *MerkleRequest) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *MerkleRequest) GetIndexes() []int32 {
+	if x != nil {
+		return x.Indexes
+	}
+	return nil
+}
+
+func (x *MerkleRequest) GetAppliedLsn() int64 {
+	if x != nil {
+		return x.AppliedLsn
+	}
+	return 0
+}
+
+type MerkleNodes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn           int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Level         int32                  `protobuf:"varint,4,opt,name=level,proto3" json:"level,omitempty"`
+	Indexes       []int32                `protobuf:"varint,5,rep,packed,name=indexes,proto3" json:"indexes,omitempty"`
+	Hashes        []uint64               `protobuf:"varint,6,rep,packed,name=hashes,proto3" json:"hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleNodes) Reset() {
+	*x = MerkleNodes{}
+	mi := &file_messages_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleNodes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleNodes) ProtoMessage() {}
+
+func (x *MerkleNodes) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleNodes.ProtoReflect.Descriptor instead.
+func (*MerkleNodes) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *MerkleNodes) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *MerkleNodes) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *MerkleNodes) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *MerkleNodes) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *MerkleNodes) GetIndexes() []int32 {
+	if x != nil {
+		return x.Indexes
+	}
+	return nil
+}
+
+func (x *MerkleNodes) GetHashes() []uint64 {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+type MerkleKeys struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp      string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term          int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn           int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	Buckets       []int32                `protobuf:"varint,4,rep,packed,name=buckets,proto3" json:"buckets,omitempty"`
+	Entries       map[string]string      `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Versions      map[string]int64       `protobuf:"bytes,6,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleKeys) Reset() {
+	*x = MerkleKeys{}
+	mi := &file_messages_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleKeys) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleKeys) ProtoMessage() {}
+
+func (x *MerkleKeys) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleKeys.ProtoReflect.Descriptor instead.
+func (*MerkleKeys) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *MerkleKeys) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *MerkleKeys) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *MerkleKeys) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *MerkleKeys) GetBuckets() []int32 {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+func (x *MerkleKeys) GetEntries() map[string]string {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *MerkleKeys) GetVersions() map[string]int64 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type MerkleResult struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	SenderIp         string                 `protobuf:"bytes,1,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	Term             int64                  `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Lsn              int64                  `protobuf:"varint,3,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	State            string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	DivergentBuckets int32                  `protobuf:"varint,5,opt,name=divergent_buckets,json=divergentBuckets,proto3" json:"divergent_buckets,omitempty"`
+	RepairedKeys     int32                  `protobuf:"varint,6,opt,name=repaired_keys,json=repairedKeys,proto3" json:"repaired_keys,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *MerkleResult) Reset() {
+	*x = MerkleResult{}
+	mi := &file_messages_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleResult) ProtoMessage() {}
+
+func (x *MerkleResult) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleResult.ProtoReflect.Descriptor instead.
+func (*MerkleResult) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *MerkleResult) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *MerkleResult) GetTerm() int64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *MerkleResult) GetLsn() int64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *MerkleResult) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *MerkleResult) GetDivergentBuckets() int32 {
+	if x != nil {
+		return x.DivergentBuckets
+	}
+	return 0
+}
+
+func (x *MerkleResult) GetRepairedKeys() int32 {
+	if x != nil {
+		return x.RepairedKeys
+	}
+	return 0
+}
+
+type TxnOp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Val           string                 `protobuf:"bytes,3,opt,name=val,proto3" json:"val,omitempty"`
+	Expect        int64                  `protobuf:"varint,4,opt,name=expect,proto3" json:"expect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnOp) Reset() {
+	*x = TxnOp{}
+	mi := &file_messages_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnOp) ProtoMessage() {}
+
+func (x *TxnOp) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnOp.ProtoReflect.Descriptor instead.
+func (*TxnOp) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *TxnOp) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TxnOp) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *TxnOp) GetVal() string {
+	if x != nil {
+		return x.Val
+	}
+	return ""
+}
+
+func (x *TxnOp) GetExpect() int64 {
+	if x != nil {
+		return x.Expect
+	}
+	return 0
+}
+
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ops           []*TxnOp               `protobuf:"bytes,1,rep,name=ops,proto3" json:"ops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_messages_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_messages_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *Transaction) GetOps() []*TxnOp {
+	if x != nil {
+		return x.Ops
+	}
+	return nil
+}
+
+var File_messages_proto protoreflect.FileDescriptor
+
+const file_messages_proto_rawDesc = "" +
+	"\n" +
+	"\x0emessages.proto\x12\bmessages\"\xfc\x01\n" +
+	"\x05Write\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x04 \x01(\tR\x03val\x12\x12\n" +
+	"\x04term\x18\x05 \x01(\x03R\x04term\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x06 \x01(\x03R\tcommitLsn\x12\x18\n" +
+	"\aaborted\x18\a \x03(\x03R\aaborted\x12\x12\n" +
+	"\x04type\x18\b \x01(\tR\x04type\x12\x16\n" +
+	"\x06expect\x18\t \x01(\x03R\x06expect\x12'\n" +
+	"\x03txn\x18\n" +
+	" \x01(\v2\x15.messages.TransactionR\x03txn\"\x9c\x01\n" +
+	"\x04Read\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x18\n" +
+	"\arequest\x18\x03 \x01(\tR\arequest\x12\x12\n" +
+	"\x04term\x18\x04 \x01(\x03R\x04term\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x05 \x01(\x03R\tcommitLsn\x12\x18\n" +
+	"\aaborted\x18\x06 \x03(\x03R\aaborted\"\xc3\x01\n" +
+	"\x03Ack\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x16\n" +
+	"\x06commit\x18\x03 \x01(\bR\x06commit\x12\x12\n" +
+	"\x04term\x18\x04 \x01(\x03R\x04term\x12\x14\n" +
+	"\x05batch\x18\x05 \x01(\bR\x05batch\x12\x1f\n" +
+	"\vapplied_lsn\x18\x06 \x01(\x03R\n" +
+	"appliedLsn\x12\x14\n" +
+	"\x05chain\x18\a \x01(\bR\x05chain\x12\x14\n" +
+	"\x05abort\x18\b \x01(\bR\x05abort\"K\n" +
+	"\x06Commit\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\"W\n" +
+	"\tSubscribe\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x19\n" +
+	"\blast_lsn\x18\x02 \x01(\x03R\alastLsn\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\"0\n" +
+	"\x04Peer\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"\x8e\x02\n" +
+	"\n" +
+	"Membership\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12!\n" +
+	"\fcluster_size\x18\x03 \x01(\x05R\vclusterSize\x12$\n" +
+	"\x05peers\x18\x04 \x03(\v2\x0e.messages.PeerR\x05peers\x12!\n" +
+	"\fprimary_http\x18\x05 \x01(\tR\vprimaryHttp\x12$\n" +
+	"\x05chain\x18\x06 \x03(\v2\x0e.messages.PeerR\x05chain\x12\x14\n" +
+	"\x05shard\x18\a \x01(\tR\x05shard\x12'\n" +
+	"\x06shards\x18\b \x03(\v2\x0f.messages.ShardR\x06shards\"N\n" +
+	"\x05Shard\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\fprimary_http\x18\x02 \x01(\tR\vprimaryHttp\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\"z\n" +
+	"\vRequestVote\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x19\n" +
+	"\blast_lsn\x18\x03 \x01(\x03R\alastLsn\x12\x1f\n" +
+	"\vapplied_lsn\x18\x04 \x01(\x03R\n" +
+	"appliedLsn\"\x7f\n" +
+	"\x04Vote\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x18\n" +
+	"\agranted\x18\x03 \x01(\bR\agranted\x12,\n" +
+	"\aentries\x18\x04 \x03(\v2\x12.messages.LogEntryR\aentries\"r\n" +
+	"\n" +
+	"NewPrimary\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12!\n" +
+	"\fprimary_http\x18\x04 \x01(\tR\vprimaryHttp\"\xa9\x01\n" +
+	"\bLogEntry\x12\x10\n" +
+	"\x03lsn\x18\x01 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x04 \x01(\tR\x03val\x12\x12\n" +
+	"\x04term\x18\x05 \x01(\x03R\x04term\x12\x16\n" +
+	"\x06expect\x18\x06 \x01(\x03R\x06expect\x12'\n" +
+	"\x03txn\x18\a \x01(\v2\x15.messages.TransactionR\x03txn\"\xf4\x03\n" +
+	"\rStateTransfer\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x03 \x01(\x03R\tcommitLsn\x12!\n" +
+	"\fsnapshot_lsn\x18\x04 \x01(\x03R\vsnapshotLsn\x12A\n" +
+	"\bsnapshot\x18\x05 \x03(\v2%.messages.StateTransfer.SnapshotEntryR\bsnapshot\x12,\n" +
+	"\aentries\x18\x06 \x03(\v2\x12.messages.LogEntryR\aentries\x12Z\n" +
+	"\x11snapshot_versions\x18\a \x03(\v2-.messages.StateTransfer.SnapshotVersionsEntryR\x10snapshotVersions\x12!\n" +
+	"\fhas_snapshot\x18\b \x01(\bR\vhasSnapshot\x1a;\n" +
+	"\rSnapshotEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aC\n" +
+	"\x15SnapshotVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xd7\x03\n" +
+	"\tWalRecord\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x05 \x01(\tR\x03val\x124\n" +
+	"\x05store\x18\x06 \x03(\v2\x1e.messages.WalRecord.StoreEntryR\x05store\x12\x12\n" +
+	"\x04term\x18\a \x01(\x03R\x04term\x12+\n" +
+	"\x06config\x18\b \x01(\v2\x13.messages.WalRecordR\x06config\x12\x16\n" +
+	"\x06expect\x18\t \x01(\x03R\x06expect\x12=\n" +
+	"\bversions\x18\n" +
+	" \x03(\v2!.messages.WalRecord.VersionsEntryR\bversions\x12'\n" +
+	"\x03txn\x18\v \x01(\v2\x15.messages.TransactionR\x03txn\x1a8\n" +
+	"\n" +
+	"StoreEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a;\n" +
+	"\rVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"n\n" +
+	"\x04Nack\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12!\n" +
+	"\flast_applied\x18\x02 \x01(\x03R\vlastApplied\x12\x12\n" +
+	"\x04lsns\x18\x03 \x03(\x03R\x04lsns\x12\x12\n" +
+	"\x04term\x18\x04 \x01(\x03R\x04term\"J\n" +
+	"\x05Abort\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\"f\n" +
+	"\tStaleTerm\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12(\n" +
+	"\aprimary\x18\x03 \x01(\v2\x0e.messages.PeerR\aprimary\"\x8b\x01\n" +
+	"\fConfigChange\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03lsn\x18\x02 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x03R\x04term\x12\x0e\n" +
+	"\x02op\x18\x04 \x01(\tR\x02op\x12(\n" +
+	"\amembers\x18\x05 \x03(\v2\x0e.messages.PeerR\amembers\"\x8e\x01\n" +
+	"\tHeartbeat\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x17\n" +
+	"\asent_at\x18\x03 \x01(\x03R\x06sentAt\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x04 \x01(\x03R\tcommitLsn\x12\x18\n" +
+	"\aaborted\x18\x05 \x03(\x03R\aaborted\"y\n" +
+	"\fHeartbeatAck\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x17\n" +
+	"\asent_at\x18\x03 \x01(\x03R\x06sentAt\x12\x1f\n" +
+	"\vapplied_lsn\x18\x04 \x01(\x03R\n" +
+	"appliedLsn\"<\n" +
+	"\tReadIndex\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\"p\n" +
+	"\x0eReadIndexReply\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x03 \x01(\x03R\tcommitLsn\x12\x0e\n" +
+	"\x02ok\x18\x04 \x01(\bR\x02ok\"\xa4\x01\n" +
+	"\n" +
+	"WriteBatch\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12,\n" +
+	"\aentries\x18\x03 \x03(\v2\x12.messages.LogEntryR\aentries\x12\x1d\n" +
+	"\n" +
+	"commit_lsn\x18\x04 \x01(\x03R\tcommitLsn\x12\x18\n" +
+	"\aaborted\x18\x05 \x03(\x03R\aaborted\"\xae\x01\n" +
+	"\tRaftEntry\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x05 \x01(\tR\x03val\x12\x16\n" +
+	"\x06expect\x18\x06 \x01(\x03R\x06expect\x12'\n" +
+	"\x03txn\x18\a \x01(\v2\x15.messages.TransactionR\x03txn\"\xa8\x02\n" +
+	"\rAppendEntries\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12$\n" +
+	"\x0eprev_log_index\x18\x03 \x01(\x03R\fprevLogIndex\x12\"\n" +
+	"\rprev_log_term\x18\x04 \x01(\x03R\vprevLogTerm\x12-\n" +
+	"\aentries\x18\x05 \x03(\v2\x13.messages.RaftEntryR\aentries\x12#\n" +
+	"\rleader_commit\x18\x06 \x01(\x03R\fleaderCommit\x12\x1f\n" +
+	"\vleader_http\x18\a \x01(\tR\n" +
+	"leaderHttp\x12'\n" +
+	"\x06shards\x18\b \x03(\v2\x0f.messages.ShardR\x06shards\"\x97\x03\n" +
+	"\x0fInstallSnapshot\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x1d\n" +
+	"\n" +
+	"last_index\x18\x03 \x01(\x03R\tlastIndex\x12\x1b\n" +
+	"\tlast_term\x18\x04 \x01(\x03R\blastTerm\x12:\n" +
+	"\x05store\x18\x05 \x03(\v2$.messages.InstallSnapshot.StoreEntryR\x05store\x12\x1f\n" +
+	"\vleader_http\x18\x06 \x01(\tR\n" +
+	"leaderHttp\x12C\n" +
+	"\bversions\x18\a \x03(\v2'.messages.InstallSnapshot.VersionsEntryR\bversions\x1a8\n" +
+	"\n" +
+	"StoreEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a;\n" +
+	"\rVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xa7\x01\n" +
+	"\x12AppendEntriesReply\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x1f\n" +
+	"\vmatch_index\x18\x04 \x01(\x03R\n" +
+	"matchIndex\x12%\n" +
+	"\x0econflict_index\x18\x05 \x01(\x03R\rconflictIndex\"\x8c\x01\n" +
+	"\x0fRaftRequestVote\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12$\n" +
+	"\x0elast_log_index\x18\x03 \x01(\x03R\flastLogIndex\x12\"\n" +
+	"\rlast_log_term\x18\x04 \x01(\x03R\vlastLogTerm\"U\n" +
+	"\bRaftVote\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x18\n" +
+	"\agranted\x18\x03 \x01(\bR\agranted\"\xc4\x01\n" +
+	"\fForwardWrite\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x03 \x01(\tR\x03val\x12\x1e\n" +
+	"\n" +
+	"durability\x18\x04 \x01(\tR\n" +
+	"durability\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\tR\x04type\x12\x16\n" +
+	"\x06expect\x18\x06 \x01(\x03R\x06expect\x12'\n" +
+	"\x03txn\x18\a \x01(\v2\x15.messages.TransactionR\x03txn\"\xec\x01\n" +
+	"\x11ForwardWriteReply\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\x05R\x06status\x12\x10\n" +
+	"\x03lsn\x18\x06 \x01(\x03R\x03lsn\x12\x1e\n" +
+	"\n" +
+	"durability\x18\a \x01(\tR\n" +
+	"durability\x12\x14\n" +
+	"\x05value\x18\b \x01(\tR\x05value\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\"c\n" +
+	"\n" +
+	"MerkleRoot\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12\x12\n" +
+	"\x04root\x18\x04 \x01(\x04R\x04root\"\xa3\x01\n" +
+	"\rMerkleRequest\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12\x14\n" +
+	"\x05level\x18\x04 \x01(\x05R\x05level\x12\x18\n" +
+	"\aindexes\x18\x05 \x03(\x05R\aindexes\x12\x1f\n" +
+	"\vapplied_lsn\x18\x06 \x01(\x03R\n" +
+	"appliedLsn\"\x98\x01\n" +
+	"\vMerkleNodes\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12\x14\n" +
+	"\x05level\x18\x04 \x01(\x05R\x05level\x12\x18\n" +
+	"\aindexes\x18\x05 \x03(\x05R\aindexes\x12\x16\n" +
+	"\x06hashes\x18\x06 \x03(\x04R\x06hashes\"\xdf\x02\n" +
+	"\n" +
+	"MerkleKeys\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12\x18\n" +
+	"\abuckets\x18\x04 \x03(\x05R\abuckets\x12;\n" +
+	"\aentries\x18\x05 \x03(\v2!.messages.MerkleKeys.EntriesEntryR\aentries\x12>\n" +
+	"\bversions\x18\x06 \x03(\v2\".messages.MerkleKeys.VersionsEntryR\bversions\x1a:\n" +
+	"\fEntriesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a;\n" +
+	"\rVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xb9\x01\n" +
+	"\fMerkleResult\x12\x1b\n" +
+	"\tsender_ip\x18\x01 \x01(\tR\bsenderIp\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x03R\x04term\x12\x10\n" +
+	"\x03lsn\x18\x03 \x01(\x03R\x03lsn\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12+\n" +
+	"\x11divergent_buckets\x18\x05 \x01(\x05R\x10divergentBuckets\x12#\n" +
+	"\rrepaired_keys\x18\x06 \x01(\x05R\frepairedKeys\"W\n" +
+	"\x05TxnOp\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x10\n" +
+	"\x03val\x18\x03 \x01(\tR\x03val\x12\x16\n" +
+	"\x06expect\x18\x04 \x01(\x03R\x06expect\"0\n" +
+	"\vTransaction\x12!\n" +
+	"\x03ops\x18\x01 \x03(\v2\x0f.messages.TxnOpR\x03opsB\fZ\n" +
 	"./messagesb\x06proto3"
 
 var (
@@ -333,20 +3288,86 @@ func file_messages_proto_rawDescGZIP() []byte {
 	return file_messages_proto_rawDescData
 }
 
-var file_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 46)
 var file_messages_proto_goTypes = []any{
-	(*Write)(nil),     // 0: messages.Write
-	(*Read)(nil),      // 1: messages.Read
-	(*Ack)(nil),       // 2: messages.Ack
-	(*Commit)(nil),    // 3: messages.Commit
-	(*Subscribe)(nil), // 4: messages.Subscribe
+	(*Write)(nil),              // 0: messages.Write
+	(*Read)(nil),               // 1: messages.Read
+	(*Ack)(nil),                // 2: messages.Ack
+	(*Commit)(nil),             // 3: messages.Commit
+	(*Subscribe)(nil),          // 4: messages.Subscribe
+	(*Peer)(nil),               // 5: messages.Peer
+	(*Membership)(nil),         // 6: messages.Membership
+	(*Shard)(nil),              // 7: messages.Shard
+	(*RequestVote)(nil),        // 8: messages.RequestVote
+	(*Vote)(nil),               // 9: messages.Vote
+	(*NewPrimary)(nil),         // 10: messages.NewPrimary
+	(*LogEntry)(nil),           // 11: messages.LogEntry
+	(*StateTransfer)(nil),      // 12: messages.StateTransfer
+	(*WalRecord)(nil),          // 13: messages.WalRecord
+	(*Nack)(nil),               // 14: messages.Nack
+	(*Abort)(nil),              // 15: messages.Abort
+	(*StaleTerm)(nil),          // 16: messages.StaleTerm
+	(*ConfigChange)(nil),       // 17: messages.ConfigChange
+	(*Heartbeat)(nil),          // 18: messages.Heartbeat
+	(*HeartbeatAck)(nil),       // 19: messages.HeartbeatAck
+	(*ReadIndex)(nil),          // 20: messages.ReadIndex
+	(*ReadIndexReply)(nil),     // 21: messages.ReadIndexReply
+	(*WriteBatch)(nil),         // 22: messages.WriteBatch
+	(*RaftEntry)(nil),          // 23: messages.RaftEntry
+	(*AppendEntries)(nil),      // 24: messages.AppendEntries
+	(*InstallSnapshot)(nil),    // 25: messages.InstallSnapshot
+	(*AppendEntriesReply)(nil), // 26: messages.AppendEntriesReply
+	(*RaftRequestVote)(nil),    // 27: messages.RaftRequestVote
+	(*RaftVote)(nil),           // 28: messages.RaftVote
+	(*ForwardWrite)(nil),       // 29: messages.ForwardWrite
+	(*ForwardWriteReply)(nil),  // 30: messages.ForwardWriteReply
+	(*MerkleRoot)(nil),         // 31: messages.MerkleRoot
+	(*MerkleRequest)(nil),      // 32: messages.MerkleRequest
+	(*MerkleNodes)(nil),        // 33: messages.MerkleNodes
+	(*MerkleKeys)(nil),         // 34: messages.MerkleKeys
+	(*MerkleResult)(nil),       // 35: messages.MerkleResult
+	(*TxnOp)(nil),              // 36: messages.TxnOp
+	(*Transaction)(nil),        // 37: messages.Transaction
+	nil,                        // 38: messages.StateTransfer.SnapshotEntry
+	nil,                        // 39: messages.StateTransfer.SnapshotVersionsEntry
+	nil,                        // 40: messages.WalRecord.StoreEntry
+	nil,                        // 41: messages.WalRecord.VersionsEntry
+	nil,                        // 42: messages.InstallSnapshot.StoreEntry
+	nil,                        // 43: messages.InstallSnapshot.VersionsEntry
+	nil,                        // 44: messages.MerkleKeys.EntriesEntry
+	nil,                        // 45: messages.MerkleKeys.VersionsEntry
 }
 var file_messages_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	37, // 0: messages.Write.txn:type_name -> messages.Transaction
+	5,  // 1: messages.Membership.peers:type_name -> messages.Peer
+	5,  // 2: messages.Membership.chain:type_name -> messages.Peer
+	7,  // 3: messages.Membership.shards:type_name -> messages.Shard
+	11, // 4: messages.Vote.entries:type_name -> messages.LogEntry
+	37, // 5: messages.LogEntry.txn:type_name -> messages.Transaction
+	38, // 6: messages.StateTransfer.snapshot:type_name -> messages.StateTransfer.SnapshotEntry
+	11, // 7: messages.StateTransfer.entries:type_name -> messages.LogEntry
+	39, // 8: messages.StateTransfer.snapshot_versions:type_name -> messages.StateTransfer.SnapshotVersionsEntry
+	40, // 9: messages.WalRecord.store:type_name -> messages.WalRecord.StoreEntry
+	13, // 10: messages.WalRecord.config:type_name -> messages.WalRecord
+	41, // 11: messages.WalRecord.versions:type_name -> messages.WalRecord.VersionsEntry
+	37, // 12: messages.WalRecord.txn:type_name -> messages.Transaction
+	5,  // 13: messages.StaleTerm.primary:type_name -> messages.Peer
+	5,  // 14: messages.ConfigChange.members:type_name -> messages.Peer
+	11, // 15: messages.WriteBatch.entries:type_name -> messages.LogEntry
+	37, // 16: messages.RaftEntry.txn:type_name -> messages.Transaction
+	23, // 17: messages.AppendEntries.entries:type_name -> messages.RaftEntry
+	7,  // 18: messages.AppendEntries.shards:type_name -> messages.Shard
+	42, // 19: messages.InstallSnapshot.store:type_name -> messages.InstallSnapshot.StoreEntry
+	43, // 20: messages.InstallSnapshot.versions:type_name -> messages.InstallSnapshot.VersionsEntry
+	37, // 21: messages.ForwardWrite.txn:type_name -> messages.Transaction
+	44, // 22: messages.MerkleKeys.entries:type_name -> messages.MerkleKeys.EntriesEntry
+	45, // 23: messages.MerkleKeys.versions:type_name -> messages.MerkleKeys.VersionsEntry
+	36, // 24: messages.Transaction.ops:type_name -> messages.TxnOp
+	25, // [25:25] is the sub-list for method output_type
+	25, // [25:25] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
 }
 
 func init() { file_messages_proto_init() }
@@ -360,7 +3381,7 @@ func file_messages_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_messages_proto_rawDesc), len(file_messages_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   46,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
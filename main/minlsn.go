@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// minLSNPollInterval is how often a read waiting on min_lsn rechecks lastAppliedLSN
+const minLSNPollInterval = 5 * time.Millisecond
+
+// httpAddress is this node's client-facing host:port, advertised to backups so they can redirect
+func (a *Actor) httpAddress() string {
+	return clientAddress(a.self.Address, a.httpPort)
+}
+
+// clientAddress is the client-facing host:port of a node whose actor system listens on actorAddress
+func clientAddress(actorAddress string, httpPort int) string {
+	host, _, err := net.SplitHostPort(actorAddress)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(httpPort))
+}
+
+// waitForLSN blocks until the applied LSN reaches lsn or timeout passes, reporting whether it did
+func (s *Server) waitForLSN(lsn int64, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for s.replicator.AppliedLSN() < lsn {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(minLSNPollInterval)
+	}
+	return true
+}
+
+// awaitMinLSN holds a read until this node has applied the client's min_lsn token. A backup that
+// doesn't catch up within minLSNWait redirects the client to the primary; it returns false once it
+// has answered the client itself.
+func (s *Server) awaitMinLSN(w http.ResponseWriter, r *http.Request, key string) bool {
+	param := r.URL.Query().Get("min_lsn")
+	if param == "" {
+		return true
+	}
+	minLSN, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || minLSN < 0 {
+		s.sendError(w, "min_lsn must be a non-negative integer", http.StatusBadRequest)
+		return false
+	}
+	if s.waitForLSN(minLSN, s.minLSNWait) {
+		return true
+	}
+
+	s.redirectToPrimary(w, r, key, fmt.Sprintf("Not caught up to min_lsn %d (lastAppliedLSN=%d)", minLSN, s.replicator.AppliedLSN()))
+	return false
+}
+
+// redirectToPrimary sends a backup's client to the same GET on the primary, or fails with reason
+// when this node is the primary or doesn't know where it is
+func (s *Server) redirectToPrimary(w http.ResponseWriter, r *http.Request, key, reason string) {
+	primary := s.replicator.PrimaryHTTP()
+	if s.replicator.IsPrimary() || primary == "" {
+		s.sendError(w, reason, http.StatusServiceUnavailable)
+		return
+	}
+	target := url.URL{Scheme: "http", Host: primary, Path: "/" + key, RawQuery: r.URL.RawQuery}
+	log.Printf("Backup: %s, redirecting to %s", reason, target.String())
+	http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+}
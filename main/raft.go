@@ -0,0 +1,720 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// raftRole is a Raft node's role in its current term
+type raftRole string
+
+const (
+	raftFollower  raftRole = "Follower"
+	raftCandidate raftRole = "Candidate"
+	raftLeader    raftRole = "Leader"
+)
+
+const (
+	raftActorName         = "raft"
+	raftTickInterval      = 50 * time.Millisecond
+	raftHeartbeatInterval = 100 * time.Millisecond // Well below electionTimeoutMin
+	raftMaxEntriesPerMsg  = 500
+	raftSnapshotResend    = time.Second // How long the leader waits for a follower to install a snapshot before sending another
+)
+
+// raftTick is a local message that drives election timeouts and leader heartbeats
+type raftTick struct{}
+
+// raftPropose is a local message carrying a client request into the Raft actor
+type raftPropose struct {
+	req *Request
+}
+
+// RaftNode replicates the store with Raft: a leader elected by majority vote appends client requests
+// to its log, followers accept entries only where their log matches the leader's, and an entry is
+// applied once a majority stores it in the leader's term (or it precedes one that is).
+type RaftNode struct {
+	system     *actor.ActorSystem
+	self       *actor.PID
+	peers      []*actor.PID // Every other node in the fixed cluster
+	httpPort   int
+	minLSNWait time.Duration
+	Server     *Server
+	storage    *raftStorage
+
+	mu         sync.Mutex // Guards role, leader, leaderHTTP, store, versions, tombstones and history, which HTTP handlers read
+	role       raftRole
+	leader     *actor.PID
+	leaderHTTP string
+	store      map[string]string
+	versions   map[string]int64 // Key => index of the entry that last set it
+	tombstones map[string]int64 // Deleted key => index of the delete, until a snapshot compacts it
+	history    *storeHistory    // Versions of every key since history.floor, for reads at an index
+
+	historyRetention int64 // Applied entries of history kept (0 disables)
+	historyCollected int64 // Applied index when the history was last pruned
+	currentTerm      atomic.Int64
+	votedFor         string
+	log              []*messages.RaftEntry // log[i].Index == logStart+i; log[0] stands for the snapshot
+	logStart         int64                 // Index of the last entry compacted into the snapshot, 0 before the first
+	commitIndex      int64
+	lastApplied      atomic.Int64
+
+	snapshotEvery int64 // Applied entries between snapshots and log compaction (0 disables)
+
+	electionDeadline time.Time
+	lastBroadcast    time.Time
+	votes            map[string]bool
+	nextIndex        map[string]int64     // Leader: next index to send to each follower
+	matchIndex       map[string]int64     // Leader: highest index known to be stored on each follower
+	lastContact      map[string]time.Time // Leader: when each follower last answered
+	snapshotSent     map[string]time.Time // Leader: when each follower was last sent a snapshot
+	proposals        map[int64]int64      // Leader: index => term of entries a client is waiting on
+}
+
+// NewRaftNode builds a follower from its persisted term, vote, snapshot and log. The snapshot is
+// committed, so it serves it straight away; the entries after it apply once a leader commits them.
+func NewRaftNode(system *actor.ActorSystem, peers []*actor.PID, httpPort int, minLSNWait time.Duration, historyRetention, snapshotEvery int64,
+	storage *raftStorage, recovered *raftRecovered) *RaftNode {
+	snapshot := recovered.snapshot
+	n := &RaftNode{
+		system:        system,
+		peers:         peers,
+		httpPort:      httpPort,
+		minLSNWait:    minLSNWait,
+		storage:       storage,
+		role:          raftFollower,
+		store:         snapshot.store,
+		versions:      snapshot.versions,
+		tombstones:    make(map[string]int64),
+		history:       newStoreHistory(snapshot.store, snapshot.versions, snapshot.index),
+		votedFor:      recovered.votedFor,
+		log:           recovered.entries,
+		logStart:      snapshot.index,
+		commitIndex:   snapshot.index,
+		snapshotEvery: snapshotEvery,
+		proposals:     make(map[int64]int64),
+	}
+	n.currentTerm.Store(recovered.term)
+	n.lastApplied.Store(snapshot.index)
+	n.historyRetention = historyRetention
+	n.historyCollected = snapshot.index
+	n.Server = NewServer(n, httpPort, minLSNWait)
+	return n
+}
+
+// raftMajority is the number of nodes, out of clusterSize, that make a Raft majority
+func raftMajority(clusterSize int) int {
+	return clusterSize/2 + 1
+}
+
+func (n *RaftNode) Receive(ctx actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case *actor.Started:
+		n.self = ctx.Self()
+		n.resetElectionDeadline()
+		n.startTicker()
+		n.Server.Start()
+		log.Printf("Raft: Started %s in term %d with %d logged entries, peers %v\n",
+			n.self.Address, n.currentTerm.Load(), n.lastIndex(), n.peers)
+	case *raftTick:
+		n.handleTick(ctx)
+	case *raftPropose:
+		n.propose(ctx, msg.req)
+	case *messages.RaftRequestVote:
+		n.handleRequestVote(ctx, msg)
+	case *messages.RaftVote:
+		n.handleVote(ctx, msg)
+	case *messages.AppendEntries:
+		n.handleAppendEntries(ctx, msg)
+	case *messages.AppendEntriesReply:
+		n.handleAppendEntriesReply(ctx, msg)
+	case *messages.InstallSnapshot:
+		n.handleInstallSnapshot(ctx, msg)
+	case *messages.ReadIndex:
+		n.handleReadIndex(ctx, msg)
+	case *messages.ForwardWrite:
+		n.Server.answerForwardedWrite(n.system, ctx.Sender(), msg)
+	}
+}
+
+// startTicker drives handleTick from a background goroutine
+func (n *RaftNode) startTicker() {
+	self := n.self
+	go func() {
+		ticker := time.NewTicker(raftTickInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n.system.Root.Send(self, &raftTick{})
+		}
+	}()
+}
+
+// handleTick starts an election once the election timeout passes without a leader,
+// and makes the leader heartbeat followers that got nothing else recently
+func (n *RaftNode) handleTick(ctx actor.Context) {
+	if n.role == raftLeader {
+		if time.Since(n.lastBroadcast) >= raftHeartbeatInterval {
+			n.broadcastAppendEntries(ctx)
+		}
+		return
+	}
+	if time.Now().After(n.electionDeadline) {
+		n.startElection(ctx)
+	}
+}
+
+func (n *RaftNode) resetElectionDeadline() {
+	timeout := electionTimeoutMin + time.Duration(rand.Int63n(int64(electionTimeoutMax-electionTimeoutMin)))
+	n.electionDeadline = time.Now().Add(timeout)
+}
+
+func (n *RaftNode) lastIndex() int64 {
+	return n.logStart + int64(len(n.log)-1)
+}
+
+// entry is the logged entry at index, which must be between logStart and lastIndex
+func (n *RaftNode) entry(index int64) *messages.RaftEntry {
+	return n.log[index-n.logStart]
+}
+
+func (n *RaftNode) lastTerm() int64 {
+	return n.log[len(n.log)-1].Term
+}
+
+// persistState saves the term and vote; callers persist before sending anything that depends on them
+func (n *RaftNode) persistState() {
+	if err := n.storage.SaveState(n.currentTerm.Load(), n.votedFor); err != nil {
+		log.Printf("Raft: Failed to persist term %d: %v\n", n.currentTerm.Load(), err)
+	}
+}
+
+// observeTerm adopts a newer term seen in any message and falls back to follower
+func (n *RaftNode) observeTerm(term int64) {
+	if term <= n.currentTerm.Load() {
+		return
+	}
+	log.Printf("Raft: Saw term %d (current term %d), becoming follower\n", term, n.currentTerm.Load())
+	n.currentTerm.Store(term)
+	n.votedFor = ""
+	n.persistState()
+	n.becomeFollower(nil, "")
+}
+
+// becomeFollower follows leader (nil while unknown), failing clients waiting on this node as leader
+func (n *RaftNode) becomeFollower(leader *actor.PID, leaderHTTP string) {
+	n.mu.Lock()
+	wasLeader := n.role == raftLeader
+	n.role = raftFollower
+	n.leader = leader
+	if leaderHTTP != "" || leader == nil {
+		n.leaderHTTP = leaderHTTP
+	}
+	n.mu.Unlock()
+
+	if wasLeader {
+		n.proposals = make(map[int64]int64)
+		n.Server.FailPendingRequests(&Response{
+			Success: false,
+			Error:   "Raft leader stepped down, retry against the new leader",
+			Status:  http.StatusServiceUnavailable,
+		})
+	}
+}
+
+// startElection votes for itself in a new term and asks every peer for a vote
+func (n *RaftNode) startElection(ctx actor.Context) {
+	term := n.currentTerm.Add(1)
+	n.votedFor = n.self.Address
+	n.persistState() // Before any RequestVote leaves, so a restart can't vote twice in this term
+	n.mu.Lock()
+	n.role = raftCandidate
+	n.leader = nil
+	n.leaderHTTP = ""
+	n.mu.Unlock()
+	n.votes = map[string]bool{n.self.Address: true}
+	n.resetElectionDeadline()
+	log.Printf("Raft: Starting election for term %d (lastIndex=%d, lastTerm=%d)\n", term, n.lastIndex(), n.lastTerm())
+
+	if len(n.votes) >= raftMajority(len(n.peers)+1) {
+		n.becomeLeader(ctx)
+		return
+	}
+	vote := &messages.RaftRequestVote{Term: term, LastLogIndex: n.lastIndex(), LastLogTerm: n.lastTerm()}
+	for _, peer := range n.peers {
+		ctx.Request(peer, vote)
+	}
+}
+
+// handleRequestVote grants one vote per term, to a candidate whose log is at least as up to date
+func (n *RaftNode) handleRequestVote(ctx actor.Context, msg *messages.RaftRequestVote) {
+	n.observeTerm(msg.Term)
+	candidate := ctx.Sender().Address
+	upToDate := msg.LastLogTerm > n.lastTerm() || (msg.LastLogTerm == n.lastTerm() && msg.LastLogIndex >= n.lastIndex())
+	granted := msg.Term == n.currentTerm.Load() && (n.votedFor == "" || n.votedFor == candidate) && upToDate
+	if granted {
+		n.votedFor = candidate
+		n.persistState()
+		n.resetElectionDeadline()
+	}
+
+	log.Printf("Raft: RequestVote(term=%d, lastIndex=%d, lastTerm=%d) from %s, granted=%t\n",
+		msg.Term, msg.LastLogIndex, msg.LastLogTerm, candidate, granted)
+	ctx.Request(ctx.Sender(), &messages.RaftVote{Term: n.currentTerm.Load(), Granted: granted})
+}
+
+// handleVote counts votes for the current term and takes over on a majority
+func (n *RaftNode) handleVote(ctx actor.Context, msg *messages.RaftVote) {
+	n.observeTerm(msg.Term)
+	if n.role != raftCandidate || msg.Term != n.currentTerm.Load() || !msg.Granted {
+		return
+	}
+	n.votes[ctx.Sender().Address] = true
+	log.Printf("Raft: Received vote for term %d (%d/%d)\n", msg.Term, len(n.votes), raftMajority(len(n.peers)+1))
+	if len(n.votes) >= raftMajority(len(n.peers)+1) {
+		n.becomeLeader(ctx)
+	}
+}
+
+// becomeLeader starts replicating to every follower from the end of this node's log
+func (n *RaftNode) becomeLeader(ctx actor.Context) {
+	n.mu.Lock()
+	n.role = raftLeader
+	n.leader = n.self
+	n.leaderHTTP = clientAddress(n.self.Address, n.httpPort)
+	n.mu.Unlock()
+
+	n.nextIndex = make(map[string]int64, len(n.peers))
+	n.matchIndex = make(map[string]int64, len(n.peers))
+	n.lastContact = make(map[string]time.Time, len(n.peers))
+	n.snapshotSent = make(map[string]time.Time, len(n.peers))
+	for _, peer := range n.peers {
+		n.nextIndex[peer.Address] = n.lastIndex() + 1
+	}
+	log.Printf("Raft: Elected leader for term %d at index %d\n", n.currentTerm.Load(), n.lastIndex())
+	n.Server.AnnounceShardPrimary(clientAddress(n.self.Address, n.httpPort), n.currentTerm.Load())
+
+	// Entries from earlier terms only commit along with one from this term
+	if _, err := n.appendLocal(&messages.RaftEntry{Type: "NOOP"}); err != nil {
+		log.Printf("Raft: Failed to log no-op for term %d: %v\n", n.currentTerm.Load(), err)
+	}
+	n.broadcastAppendEntries(ctx)
+	n.advanceCommitIndex()
+}
+
+// appendLocal stamps entry with the next index and current term and logs it on the leader
+func (n *RaftNode) appendLocal(entry *messages.RaftEntry) (int64, error) {
+	entry.Index = n.lastIndex() + 1
+	entry.Term = n.currentTerm.Load()
+	if err := n.storage.Append([]*messages.RaftEntry{entry}); err != nil {
+		return 0, err
+	}
+	n.log = append(n.log, entry)
+	return entry.Index, nil
+}
+
+// propose appends a client request to the leader's log and replicates it
+func (n *RaftNode) propose(ctx actor.Context, req *Request) {
+	tempLSN := req.LSN
+	if n.role != raftLeader {
+		n.Server.CompletePendingRequest(tempLSN, &Response{
+			Success: false,
+			Key:     req.Key,
+			Error:   "Not the Raft leader",
+			Status:  http.StatusServiceUnavailable,
+		})
+		return
+	}
+
+	index, err := n.appendLocal(&messages.RaftEntry{Type: req.Type, Key: req.Key, Val: req.Val, Expect: req.Expect, Txn: txnToWire(req.Ops)})
+	if err != nil {
+		log.Printf("Raft: Failed to log %s(Key=%s): %v\n", req.Type, req.Key, err)
+		n.Server.CompletePendingRequest(tempLSN, &Response{
+			Success: false,
+			Key:     req.Key,
+			Error:   "Failed to persist request",
+			Status:  http.StatusInternalServerError,
+		})
+		return
+	}
+	req.LSN = index
+	n.Server.UpdatePendingRequestLSN(tempLSN, index, req)
+	n.proposals[index] = n.currentTerm.Load()
+	log.Printf("Raft: Appended %s(Key=%s) at index %d, term %d\n", req.Type, req.Key, index, n.currentTerm.Load())
+
+	n.broadcastAppendEntries(ctx)
+	n.advanceCommitIndex()
+}
+
+// broadcastAppendEntries sends every follower the entries it is missing, or an empty heartbeat
+func (n *RaftNode) broadcastAppendEntries(ctx actor.Context) {
+	for _, peer := range n.peers {
+		n.sendAppendEntries(ctx, peer)
+	}
+	n.lastBroadcast = time.Now()
+}
+
+// sendAppendEntries ships entries from the follower's nextIndex, pipelining: nextIndex moves past them
+// straight away and only falls back if the follower rejects them
+func (n *RaftNode) sendAppendEntries(ctx actor.Context, peer *actor.PID) {
+	name := peer.Address
+	next := min(max(n.nextIndex[name], 1), n.lastIndex()+1)
+	if next <= n.logStart {
+		n.sendSnapshot(ctx, peer) // The entries it needs were compacted away
+		return
+	}
+	end := min(n.lastIndex()+1, next+raftMaxEntriesPerMsg)
+	_, shards := shardsToWire(n.Server.shards.Load()) // Followers learn other groups' new primaries from the leader
+
+	ctx.Request(peer, &messages.AppendEntries{
+		Term:         n.currentTerm.Load(),
+		PrevLogIndex: next - 1,
+		PrevLogTerm:  n.entry(next - 1).Term,
+		Entries:      n.log[next-n.logStart : end-n.logStart],
+		LeaderCommit: n.commitIndex,
+		LeaderHttp:   clientAddress(n.self.Address, n.httpPort),
+		Shards:       shards,
+	})
+	n.nextIndex[name] = end
+}
+
+// handleAppendEntries accepts entries only where the follower's log matches the leader's at
+// PrevLogIndex, replacing any conflicting suffix, then applies up to the leader's commit index
+func (n *RaftNode) handleAppendEntries(ctx actor.Context, msg *messages.AppendEntries) {
+	reply := &messages.AppendEntriesReply{}
+	if msg.Term < n.currentTerm.Load() {
+		reply.Term = n.currentTerm.Load()
+		ctx.Request(ctx.Sender(), reply)
+		return
+	}
+	n.observeTerm(msg.Term)
+	if n.role != raftFollower || !ctx.Sender().Equal(n.leader) {
+		log.Printf("Raft: Following leader %s for term %d\n", ctx.Sender().Address, msg.Term)
+	}
+	n.becomeFollower(ctx.Sender(), msg.LeaderHttp)
+	n.Server.learnShards(shardListFromWire(msg.Shards))
+	n.resetElectionDeadline()
+	reply.Term = n.currentTerm.Load()
+
+	if msg.PrevLogIndex > n.lastIndex() {
+		reply.ConflictIndex = n.lastIndex() + 1
+		ctx.Request(ctx.Sender(), reply)
+		return
+	}
+	entries := msg.Entries
+	if msg.PrevLogIndex < n.logStart {
+		// Everything up to the snapshot is committed, so it matches the leader's log already
+		for len(entries) > 0 && entries[0].Index <= n.logStart {
+			entries = entries[1:]
+		}
+	} else if conflictTerm := n.entry(msg.PrevLogIndex).Term; conflictTerm != msg.PrevLogTerm {
+		// Skip back over the whole conflicting term instead of one entry per round trip
+		first := msg.PrevLogIndex
+		for first > n.logStart+1 && n.entry(first-1).Term == conflictTerm {
+			first--
+		}
+		reply.ConflictIndex = max(first, n.commitIndex+1)
+		ctx.Request(ctx.Sender(), reply)
+		return
+	}
+
+	var appended []*messages.RaftEntry
+	for i, entry := range entries {
+		if entry.Index <= n.lastIndex() {
+			if n.entry(entry.Index).Term == entry.Term {
+				continue // Already have it, e.g. a pipelined resend
+			}
+			if err := n.truncateLog(entry.Index); err != nil {
+				log.Printf("Raft: Failed to truncate log at index %d, not acking: %v\n", entry.Index, err)
+				return
+			}
+		}
+		appended = entries[i:]
+		break
+	}
+	if len(appended) > 0 {
+		if err := n.storage.Append(appended); err != nil {
+			log.Printf("Raft: Failed to log %d entries, not acking: %v\n", len(appended), err)
+			return
+		}
+		n.log = append(n.log, appended...)
+	}
+
+	matched := msg.PrevLogIndex + int64(len(msg.Entries))
+	if msg.LeaderCommit > n.commitIndex {
+		n.commitIndex = max(n.commitIndex, min(msg.LeaderCommit, matched))
+		n.applyCommitted()
+	}
+	reply.Success = true
+	reply.MatchIndex = matched
+	ctx.Request(ctx.Sender(), reply)
+}
+
+// truncateLog drops every entry from index on. Committed entries are never overwritten.
+func (n *RaftNode) truncateLog(index int64) error {
+	if index <= n.commitIndex {
+		return fmt.Errorf("index %d is committed (commitIndex=%d)", index, n.commitIndex)
+	}
+	log.Printf("Raft: Discarding entries %d-%d that conflict with the leader\n", index, n.lastIndex())
+	if err := n.storage.TruncateFrom(index); err != nil {
+		return err
+	}
+	n.log = n.log[:index-n.logStart]
+	return nil
+}
+
+// handleAppendEntriesReply advances a follower's match index, or backs its next index up to the
+// point where its log diverged and resends from there
+func (n *RaftNode) handleAppendEntriesReply(ctx actor.Context, msg *messages.AppendEntriesReply) {
+	n.observeTerm(msg.Term)
+	if n.role != raftLeader || msg.Term != n.currentTerm.Load() {
+		return
+	}
+	peer := ctx.Sender().Address
+	n.lastContact[peer] = time.Now()
+
+	if msg.Success {
+		n.matchIndex[peer] = max(n.matchIndex[peer], msg.MatchIndex)
+		n.nextIndex[peer] = max(n.nextIndex[peer], n.matchIndex[peer]+1)
+		n.advanceCommitIndex()
+		return
+	}
+	n.nextIndex[peer] = max(n.matchIndex[peer]+1, min(msg.ConflictIndex, n.lastIndex()+1))
+	log.Printf("Raft: %s rejected entries, resending from index %d\n", peer, n.nextIndex[peer])
+	n.sendAppendEntries(ctx, ctx.Sender())
+}
+
+// advanceCommitIndex commits the highest entry of the current term stored on a majority,
+// which commits every entry before it too
+func (n *RaftNode) advanceCommitIndex() {
+	for index := n.lastIndex(); index > n.commitIndex; index-- {
+		if n.entry(index).Term != n.currentTerm.Load() {
+			break // Older entries can't be counted directly
+		}
+		stored := 1
+		for _, peer := range n.peers {
+			if n.matchIndex[peer.Address] >= index {
+				stored++
+			}
+		}
+		if stored >= raftMajority(len(n.peers)+1) {
+			n.commitIndex = index
+			n.applyCommitted()
+			return
+		}
+	}
+}
+
+// applyCommitted applies committed entries in order under one lock, then answers their clients
+func (n *RaftNode) applyCommitted() {
+	type applied struct {
+		entry   *messages.RaftEntry
+		value   string
+		version int64
+		failed  *Response // A read of a missing key, or the 412 answer when a version condition didn't hold
+	}
+	results := make([]applied, 0)
+
+	n.mu.Lock()
+	for index := n.lastApplied.Load() + 1; index <= n.commitIndex; index++ {
+		entry := n.entry(index)
+		result := applied{entry: entry}
+		switch {
+		case entry.Type == "READ":
+			var exists bool
+			if result.value, exists = n.store[entry.Key]; !exists {
+				result.failed = keyNotFound(entry.Key, n.tombstones)
+			}
+			result.version = n.versions[entry.Key]
+		case isMutation(entry.Type):
+			req := &Request{Type: entry.Type, Key: entry.Key, Val: entry.Val, Expect: entry.Expect, Ops: txnFromWire(entry.Txn)}
+			if result.failed = applyMutation(n, req, index); result.failed == nil && entry.Type != "DELETE" {
+				result.version = index
+			}
+		}
+		n.lastApplied.Store(index)
+		results = append(results, result)
+	}
+	n.collectHistory()
+	n.mu.Unlock()
+	n.maybeSnapshot()
+
+	for _, result := range results {
+		entry := result.entry
+		term, proposed := n.proposals[entry.Index]
+		if !proposed {
+			continue
+		}
+		delete(n.proposals, entry.Index)
+		switch {
+		case term != entry.Term:
+			n.Server.CompletePendingRequest(entry.Index, &Response{
+				Success: false,
+				Key:     entry.Key,
+				Error:   "Entry was overwritten by a new Raft leader",
+				Status:  http.StatusServiceUnavailable,
+			})
+		case result.failed != nil:
+			n.Server.CompletePendingRequest(entry.Index, result.failed)
+		default:
+			n.Server.CompletePendingRequest(entry.Index, &Response{Success: true, Key: entry.Key, Value: result.value, Version: result.version})
+		}
+	}
+}
+
+// lookup is key's version and whether it is in the store. Caller must hold mu.
+func (n *RaftNode) lookup(key string) (int64, bool) {
+	_, exists := n.store[key]
+	return n.versions[key], exists
+}
+
+// setKey writes key at the index of the entry setting it. Caller must hold mu.
+func (n *RaftNode) setKey(key, val string, index int64) {
+	n.store[key] = val
+	n.versions[key] = index
+	if n.historyRetention > 0 {
+		n.history.record(key, index, val, false)
+	}
+	delete(n.tombstones, key)
+}
+
+// deleteKey removes key and leaves a tombstone naming the deleting index. Caller must hold mu.
+func (n *RaftNode) deleteKey(key string, index int64) {
+	delete(n.store, key)
+	delete(n.versions, key)
+	n.tombstones[key] = index
+	if n.historyRetention > 0 {
+		n.history.record(key, index, "", true)
+	}
+}
+
+// handleReadIndex gives a follower the leader's commit index, but only while this node can be
+// sure it is still leader: a majority answered within the minimum election timeout, so none of
+// them can have elected anyone else yet, and an entry of this term is committed
+func (n *RaftNode) handleReadIndex(ctx actor.Context, msg *messages.ReadIndex) {
+	n.observeTerm(msg.Term)
+	reply := &messages.ReadIndexReply{Term: n.currentTerm.Load()}
+	if n.role == raftLeader && n.entry(n.commitIndex).Term == n.currentTerm.Load() {
+		recent := 1
+		for _, peer := range n.peers {
+			if time.Since(n.lastContact[peer.Address]) < electionTimeoutMin {
+				recent++
+			}
+		}
+		if recent >= raftMajority(len(n.peers)+1) {
+			reply.CommitLsn = n.commitIndex
+			reply.Ok = true
+		}
+	}
+	ctx.Respond(reply)
+}
+
+// Write appends a client write to the leader's log
+func (n *RaftNode) Write(req *Request) {
+	n.system.Root.Send(n.self, &raftPropose{req: req})
+}
+
+// Read goes through the log on the leader, so it is linearizable, and reads the local store elsewhere
+func (n *RaftNode) Read(req *Request) {
+	if n.IsPrimary() {
+		n.system.Root.Send(n.self, &raftPropose{req: req})
+		return
+	}
+
+	n.mu.Lock()
+	val, exists := n.store[req.Key]
+	version := n.versions[req.Key]
+	notFound := keyNotFound(req.Key, n.tombstones)
+	n.mu.Unlock()
+	if !exists {
+		n.Server.CompletePendingRequest(req.LSN, notFound)
+		return
+	}
+	n.Server.CompletePendingRequest(req.LSN, &Response{Success: true, Key: req.Key, Value: val, LSN: n.lastApplied.Load(), Version: version})
+}
+
+func (n *RaftNode) IsPrimary() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.role == raftLeader
+}
+
+func (n *RaftNode) AppliedLSN() int64 {
+	return n.lastApplied.Load()
+}
+
+func (n *RaftNode) PrimaryHTTP() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.leaderHTTP
+}
+
+// ReadIndex asks the leader for a commit index it has confirmed it still leads at
+func (n *RaftNode) ReadIndex() (int64, error) {
+	n.mu.Lock()
+	leader := n.leader
+	n.mu.Unlock()
+	if leader == nil {
+		return 0, errors.New("no Raft leader to confirm the read index")
+	}
+	term := n.currentTerm.Load()
+	result, err := n.system.Root.RequestFuture(leader, &messages.ReadIndex{Term: term}, readIndexTimeout).Result()
+	if err != nil {
+		return 0, fmt.Errorf("read index from %s: %w", leader.Address, err)
+	}
+	reply, ok := result.(*messages.ReadIndexReply)
+	if !ok || !reply.Ok || reply.Term < term {
+		return 0, fmt.Errorf("%s could not confirm it is still leader", leader.Address)
+	}
+	return reply.CommitLsn, nil
+}
+
+// Durability is always quorum: Raft commits an entry once a majority stores it
+func (n *RaftNode) Durability(mode string) (Durability, error) {
+	if mode != "" && Durability(mode) != DurabilityQuorum {
+		return "", fmt.Errorf("%s=%s is not supported with Raft, which always commits on a majority", durabilityHeader, mode)
+	}
+	return DurabilityQuorum, nil
+}
+
+// MemberStatus reports the fixed Raft cluster and the leader this node knows of
+func (n *RaftNode) MemberStatus() *MemberStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := &MemberStatus{
+		Term:        n.currentTerm.Load(),
+		ClusterSize: len(n.peers) + 1,
+		Quorum:      raftMajority(len(n.peers) + 1),
+		Members:     []string{n.self.Address},
+	}
+	if n.leader != nil {
+		status.Primary = n.leader.Address
+	}
+	for _, peer := range n.peers {
+		status.Members = append(status.Members, peer.Address)
+	}
+	return status
+}
+
+// parseRaftPeers turns the -peers list of host:port actor addresses into PIDs
+func parseRaftPeers(list string) []*actor.PID {
+	peers := make([]*actor.PID, 0)
+	for _, address := range strings.Split(list, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			peers = append(peers, actor.NewPID(address, raftActorName))
+		}
+	}
+	return peers
+}
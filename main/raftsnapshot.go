@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// maybeSnapshot compacts the log once snapshotEvery entries were applied since the last snapshot:
+// the applied store is saved and the log keeps only the entries after it
+func (n *RaftNode) maybeSnapshot() {
+	applied := n.lastApplied.Load()
+	if n.snapshotEvery <= 0 || applied-n.logStart < n.snapshotEvery {
+		return
+	}
+	n.mu.Lock()
+	snapshot := newRaftSnapshot(applied, n.entry(applied).Term, n.store, n.versions)
+	compactTombstones(n.tombstones, applied)
+	n.mu.Unlock()
+
+	if err := n.compactLog(snapshot); err != nil {
+		log.Printf("Raft: Failed to snapshot at index %d: %v\n", applied, err)
+		return
+	}
+	log.Printf("Raft: Snapshot at index %d (%d keys), %d entries retained\n", applied, len(snapshot.store), len(n.log)-1)
+}
+
+// compactLog persists snapshot and drops every entry up to it. Entries after it are kept if this log
+// holds the snapshot's last entry too, since then they follow the same history.
+func (n *RaftNode) compactLog(snapshot *raftSnapshot) error {
+	var retained []*messages.RaftEntry
+	if snapshot.index <= n.lastIndex() && n.entry(snapshot.index).Term == snapshot.term {
+		retained = n.log[snapshot.index-n.logStart+1:]
+	}
+	if err := n.storage.Compact(snapshot, retained); err != nil {
+		return err
+	}
+	n.log = append([]*messages.RaftEntry{{Index: snapshot.index, Term: snapshot.term}}, retained...)
+	n.logStart = snapshot.index
+	return nil
+}
+
+// sendSnapshot catches up a follower that needs entries compacted away, with the leader's store at
+// its applied index. That index is committed, so the follower can install it outright. Rejections
+// of entries pipelined before the snapshot don't send it again while it may still be installing.
+func (n *RaftNode) sendSnapshot(ctx actor.Context, peer *actor.PID) {
+	if time.Since(n.snapshotSent[peer.Address]) < raftSnapshotResend {
+		return
+	}
+	n.snapshotSent[peer.Address] = time.Now()
+	n.mu.Lock()
+	index := n.lastApplied.Load()
+	snapshot := newRaftSnapshot(index, n.entry(index).Term, n.store, n.versions)
+	n.mu.Unlock()
+
+	log.Printf("Raft: %s needs entries before index %d, sending snapshot at index %d (%d keys)\n",
+		peer.Address, n.logStart+1, index, len(snapshot.store))
+	ctx.Request(peer, &messages.InstallSnapshot{
+		Term:       n.currentTerm.Load(),
+		LastIndex:  snapshot.index,
+		LastTerm:   snapshot.term,
+		Store:      snapshot.store,
+		Versions:   snapshot.versions,
+		LeaderHttp: clientAddress(n.self.Address, n.httpPort),
+	})
+	n.nextIndex[peer.Address] = index + 1
+}
+
+// handleInstallSnapshot replaces a lagging follower's store with the leader's snapshot and answers
+// like an AppendEntries that matched up to its last index
+func (n *RaftNode) handleInstallSnapshot(ctx actor.Context, msg *messages.InstallSnapshot) {
+	reply := &messages.AppendEntriesReply{}
+	if msg.Term < n.currentTerm.Load() {
+		reply.Term = n.currentTerm.Load()
+		ctx.Request(ctx.Sender(), reply)
+		return
+	}
+	n.observeTerm(msg.Term)
+	n.becomeFollower(ctx.Sender(), msg.LeaderHttp)
+	n.resetElectionDeadline()
+	reply.Term = n.currentTerm.Load()
+	reply.Success = true
+	reply.MatchIndex = msg.LastIndex
+
+	if msg.LastIndex <= n.lastApplied.Load() {
+		ctx.Request(ctx.Sender(), reply) // Already applied past it, e.g. a resend
+		return
+	}
+	snapshot := newRaftSnapshot(msg.LastIndex, msg.LastTerm, msg.Store, msg.Versions)
+	if err := n.compactLog(snapshot); err != nil {
+		log.Printf("Raft: Failed to install snapshot at index %d, not acking: %v\n", msg.LastIndex, err)
+		return
+	}
+
+	n.mu.Lock()
+	n.store = snapshot.store
+	n.versions = snapshot.versions
+	n.tombstones = make(map[string]int64) // The snapshot covers every delete
+	n.history = newStoreHistory(n.store, n.versions, snapshot.index)
+	n.historyCollected = snapshot.index
+	n.lastApplied.Store(snapshot.index)
+	n.mu.Unlock()
+	n.commitIndex = max(n.commitIndex, snapshot.index)
+
+	log.Printf("Raft: Installed snapshot at index %d from %s (%d keys), %d entries retained\n",
+		snapshot.index, ctx.Sender().Address, len(snapshot.store), len(n.log)-1)
+	ctx.Request(ctx.Sender(), reply)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"distributed/messages"
+)
+
+// Raft state lives next to the primary-backup WAL but in its own files
+const (
+	raftLogFile      = "raft.log"
+	raftStateFile    = "raft-state"
+	raftSnapshotFile = "raft.snap"
+)
+
+// Raft log record kinds, framed like WAL records
+const (
+	raftEntryRecord    = "RAFT_ENTRY"    // An entry at an index and term
+	raftTruncateRecord = "RAFT_TRUNCATE" // Entries from an index onwards were overwritten by a new leader
+	raftSnapshotRecord = "RAFT_SNAPSHOT" // The applied store at an index, which the log starts after
+)
+
+// raftStorage persists a Raft node's term, vote, snapshot and log. Every method syncs before
+// returning, since Raft replies promise the data is durable.
+type raftStorage struct {
+	dir  string
+	file *os.File // raft.log, opened for appending
+}
+
+// raftSnapshot is the applied store and its versions at index, the entry of term a compacted log starts after
+type raftSnapshot struct {
+	index    int64
+	term     int64
+	store    map[string]string
+	versions map[string]int64
+}
+
+// newRaftSnapshot copies store and versions, which may be nil, into a snapshot at index
+func newRaftSnapshot(index, term int64, store map[string]string, versions map[string]int64) *raftSnapshot {
+	s := &raftSnapshot{
+		index:    index,
+		term:     term,
+		store:    make(map[string]string, len(store)),
+		versions: make(map[string]int64, len(store)),
+	}
+	for key, val := range store {
+		s.store[key] = val
+		s.versions[key] = versions[key]
+	}
+	return s
+}
+
+// raftRecovered is what a Raft node restarts from
+type raftRecovered struct {
+	term     int64
+	votedFor string
+	snapshot *raftSnapshot         // An empty store at index 0 until the first snapshot
+	entries  []*messages.RaftEntry // entries[i].Index == snapshot.index+i; entries[0] stands for the snapshot
+}
+
+// openRaftStorage loads the hard state and snapshot in dir and replays the log after the snapshot
+func openRaftStorage(dir string) (*raftStorage, *raftRecovered, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	term, votedFor, err := loadRaftState(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := loadRaftSnapshot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []*messages.RaftEntry{{Index: snapshot.index, Term: snapshot.term}}
+	path := filepath.Join(dir, raftLogFile)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	var offset int64
+	header := make([]byte, walHeaderSize)
+	for {
+		record, n, err := readWALRecord(file, header)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Raft: Truncating %s at offset %d: %v\n", path, offset, err)
+			if err := file.Truncate(offset); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+		offset += n
+		switch record.Kind {
+		case raftEntryRecord:
+			entry := &messages.RaftEntry{Index: record.Lsn, Term: record.Term, Type: record.Type, Key: record.Key, Val: record.Val, Expect: record.Expect, Txn: record.Txn}
+			if entry.Index <= snapshot.index {
+				continue // Written before a crash between saving the snapshot and rewriting the log
+			}
+			if entry.Index != snapshot.index+int64(len(entries)) {
+				return nil, nil, fmt.Errorf("%s: entry %d out of order at offset %d", path, entry.Index, offset)
+			}
+			entries = append(entries, entry)
+		case raftTruncateRecord:
+			if kept := record.Lsn - snapshot.index; kept >= 1 && kept < int64(len(entries)) {
+				entries = entries[:kept]
+			}
+		}
+	}
+	file.Close()
+
+	s := &raftStorage{dir: dir}
+	if s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("Raft: Replayed %d entries after snapshot index %d from %s (term=%d, votedFor=%q)\n",
+		len(entries)-1, snapshot.index, path, term, votedFor)
+	return s, &raftRecovered{term: term, votedFor: votedFor, snapshot: snapshot, entries: entries}, nil
+}
+
+// loadRaftSnapshot reads the snapshot in dir, an empty store at index 0 if there is none
+func loadRaftSnapshot(dir string) (*raftSnapshot, error) {
+	path := filepath.Join(dir, raftSnapshotFile)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return newRaftSnapshot(0, 0, nil, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	record, _, err := readWALRecord(file, make([]byte, walHeaderSize))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("Raft: Loaded snapshot %s (index=%d, term=%d, keys=%d)\n", path, record.Lsn, record.Term, len(record.Store))
+	return newRaftSnapshot(record.Lsn, record.Term, record.Store, record.Versions), nil
+}
+
+// SaveState durably records the current term and who this node voted for in it
+func (s *raftStorage) SaveState(term int64, votedFor string) error {
+	return writeFileAtomic(filepath.Join(s.dir, raftStateFile), []byte(fmt.Sprintf("%d %s\n", term, votedFor)))
+}
+
+// loadRaftState reads the persisted term and vote, defaulting to term 0 and no vote
+func loadRaftState(dir string) (int64, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, raftStateFile))
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	fields := strings.Fields(string(data))
+	var term int64
+	if len(fields) == 0 {
+		return 0, "", fmt.Errorf("%s: empty", raftStateFile)
+	}
+	if _, err := fmt.Sscanf(fields[0], "%d", &term); err != nil {
+		return 0, "", fmt.Errorf("%s: %w", raftStateFile, err)
+	}
+	if len(fields) > 1 {
+		return term, fields[1], nil
+	}
+	return term, "", nil
+}
+
+// Append logs entries with a single sync
+func (s *raftStorage) Append(entries []*messages.RaftEntry) error {
+	return s.write(raftEntryRecords(entries))
+}
+
+// Compact durably saves snapshot, then rewrites the log to hold only retained, the entries after it.
+// A crash in between leaves the old log, whose entries up to the snapshot are skipped on replay.
+func (s *raftStorage) Compact(snapshot *raftSnapshot, retained []*messages.RaftEntry) error {
+	buf, err := encodeWALRecord(&messages.WalRecord{
+		Kind:     raftSnapshotRecord,
+		Lsn:      snapshot.index,
+		Term:     snapshot.term,
+		Store:    snapshot.store,
+		Versions: snapshot.versions,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, raftSnapshotFile), buf); err != nil {
+		return err
+	}
+
+	var data []byte
+	for _, record := range raftEntryRecords(retained) {
+		buf, err := encodeWALRecord(record)
+		if err != nil {
+			return err
+		}
+		data = append(data, buf...)
+	}
+	path := filepath.Join(s.dir, raftLogFile)
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// raftEntryRecords frames entries as log records
+func raftEntryRecords(entries []*messages.RaftEntry) []*messages.WalRecord {
+	records := make([]*messages.WalRecord, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, &messages.WalRecord{
+			Kind:   raftEntryRecord,
+			Lsn:    entry.Index,
+			Term:   entry.Term,
+			Type:   entry.Type,
+			Key:    entry.Key,
+			Val:    entry.Val,
+			Expect: entry.Expect,
+			Txn:    entry.Txn,
+		})
+	}
+	return records
+}
+
+// TruncateFrom logs that every entry at index and above was discarded
+func (s *raftStorage) TruncateFrom(index int64) error {
+	return s.write([]*messages.WalRecord{{Kind: raftTruncateRecord, Lsn: index}})
+}
+
+func (s *raftStorage) write(records []*messages.WalRecord) error {
+	for _, record := range records {
+		buf, err := encodeWALRecord(record)
+		if err != nil {
+			return err
+		}
+		if _, err := s.file.Write(buf); err != nil {
+			return err
+		}
+	}
+	return s.file.Sync()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// readIndexTimeout bounds how long a backup waits for the primary to confirm its commit index
+const readIndexTimeout = time.Second
+
+// readIndexWaiter is a ReadIndex the primary answers once a quorum acks a heartbeat sent after it arrived
+type readIndexWaiter struct {
+	sender *actor.PID
+	index  int64
+	since  int64 // monotonicNow() when the request arrived
+}
+
+// handleReadIndex replies with the primary's commit index once it has confirmed it is still primary:
+// immediately under a valid lease, otherwise after a fresh heartbeat round reaches a quorum
+func (a *Actor) handleReadIndex(ctx actor.Context, msg *messages.ReadIndex) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) || !a.isPrimary {
+		ctx.Respond(&messages.ReadIndexReply{Term: a.term.Load()})
+		return
+	}
+	// Recovered entries may hold writes the last primary already acknowledged
+	index := max(a.lastAppliedLSN.Load(), a.recoveredLSN)
+	if a.leaseValid() || quorumSize(a.subscribers+1) <= 1 {
+		ctx.Respond(&messages.ReadIndexReply{Term: a.term.Load(), CommitLsn: index, Ok: true})
+		return
+	}
+
+	a.readIndexWaiters = append(a.readIndexWaiters, &readIndexWaiter{sender: ctx.Sender(), index: index, since: monotonicNow()})
+	a.handleHeartbeatTick(ctx)
+}
+
+// confirmReadIndexes answers waiting ReadIndex requests whose heartbeat round reached a quorum,
+// and drops those the backup has already given up on
+func (a *Actor) confirmReadIndexes(ctx actor.Context) {
+	if len(a.readIndexWaiters) == 0 {
+		return
+	}
+	needed := quorumSize(a.subscribers+1) - 1
+
+	a.leaseMu.Lock()
+	defer a.leaseMu.Unlock()
+
+	waiting := a.readIndexWaiters[:0]
+	for _, waiter := range a.readIndexWaiters {
+		acked := 0
+		for _, target := range a.targets {
+			if a.leaseAcks[target.String()] >= waiter.since {
+				acked++
+			}
+		}
+		switch {
+		case acked >= needed:
+			ctx.Send(waiter.sender, &messages.ReadIndexReply{Term: a.term.Load(), CommitLsn: waiter.index, Ok: true})
+		case monotonicNow()-waiter.since < int64(readIndexTimeout):
+			waiting = append(waiting, waiter)
+		}
+	}
+	a.readIndexWaiters = waiting
+}
+
+// requestReadIndex asks the followed primary for its confirmed commit index
+func (a *Actor) requestReadIndex() (int64, error) {
+	primary := a.primaryPID
+	if primary == nil {
+		return 0, errors.New("no primary to confirm the read index")
+	}
+	term := a.term.Load()
+	result, err := a.system.Root.RequestFuture(primary, &messages.ReadIndex{Term: term}, readIndexTimeout).Result()
+	if err != nil {
+		return 0, fmt.Errorf("read index from %s: %w", primary.String(), err)
+	}
+	reply, ok := result.(*messages.ReadIndexReply)
+	if !ok || !reply.Ok || reply.Term < term {
+		return 0, fmt.Errorf("%s could not confirm it is still primary", primary.String())
+	}
+	return reply.CommitLsn, nil
+}
+
+// awaitReadIndex makes a backup GET linearizable: it reads only after applying everything the primary
+// had committed when the read started. It returns false once it has answered the client itself.
+func (s *Server) awaitReadIndex(w http.ResponseWriter, r *http.Request, key string) bool {
+	index, err := s.replicator.ReadIndex()
+	if err != nil {
+		s.redirectToPrimary(w, r, key, fmt.Sprintf("Read index unavailable: %v", err))
+		return false
+	}
+	if !s.waitForLSN(index, s.minLSNWait) {
+		s.redirectToPrimary(w, r, key, fmt.Sprintf("Not caught up to read index %d (lastAppliedLSN=%d)", index, s.replicator.AppliedLSN()))
+		return false
+	}
+	log.Printf("Backup: Read index %d reached for Key=%s", index, key)
+	return true
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+const (
+	repairInterval  = 300 * time.Millisecond // How often backups look for holes, and the minimum delay between NACKs for one LSN
+	maxNackedPerMsg = 500                    // Cap on LSNs listed in a single Nack
+	resubscribeWait = time.Second            // How long a backup waits for a state transfer before subscribing again
+)
+
+// repairTick is a local message that makes a backup look for holes in its log
+type repairTick struct{}
+
+// startRepairTimer periodically checks for holes so a backup that stops receiving messages still repairs
+func (a *Actor) startRepairTimer() {
+	self := a.self
+	go func() {
+		ticker := time.NewTicker(repairInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.system.Root.Send(self, &repairTick{})
+		}
+	}()
+}
+
+// observeLSN records the highest LSN a backup has heard of from the primary
+func (a *Actor) observeLSN(lsn int64) {
+	if lsn > a.highestSeenLSN {
+		a.highestSeenLSN = lsn
+	}
+}
+
+// findGaps lists LSNs between lastAppliedLSN and the highest LSN seen that block progress:
+// entries missing from Log, and logged entries whose Commit was lost while a later LSN is committed
+func (a *Actor) findGaps() []int64 {
+	lastApplied := a.lastAppliedLSN.Load()
+	if a.highestSeenLSN <= lastApplied {
+		return nil
+	}
+
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+	a.pendingMu.Lock()
+	defer a.pendingMu.Unlock()
+
+	// Highest LSN known to be committed; holes below it can't be an in-flight write
+	highestCommitted := lastApplied
+	for lsn := range a.pendingCommits {
+		highestCommitted = max(highestCommitted, lsn)
+	}
+
+	gaps := make([]int64, 0)
+	for lsn := lastApplied + 1; lsn <= a.highestSeenLSN && len(gaps) < maxNackedPerMsg; lsn++ {
+		if _, logged := a.Log[lsn]; !logged {
+			gaps = append(gaps, lsn)
+			continue
+		}
+		if _, queued := a.pendingCommits[lsn]; !queued && lsn < highestCommitted {
+			gaps = append(gaps, lsn)
+		}
+	}
+	return gaps
+}
+
+// requestRepair sends a Nack for holes that haven't been NACKed recently
+func (a *Actor) requestRepair(ctx actor.Context) {
+	if a.isPrimary || a.primaryPID == nil {
+		return
+	}
+	now := time.Now()
+	if a.catchingUp {
+		if now.Sub(a.subscribedAt) > resubscribeWait {
+			log.Printf("%s: No state transfer from %s yet, subscribing again\n", role(a.isPrimary), a.primaryPID.String())
+			a.subscribedAt = now
+			ctx.Request(a.primaryPID, &messages.Subscribe{LastLsn: a.lastAppliedLSN.Load(), Term: a.term.Load()})
+		}
+		return
+	}
+	lsns := make([]int64, 0)
+	for _, lsn := range a.findGaps() {
+		if last, nacked := a.nackedAt[lsn]; nacked && now.Sub(last) < repairInterval {
+			continue
+		}
+		a.nackedAt[lsn] = now
+		lsns = append(lsns, lsn)
+	}
+	for lsn := range a.nackedAt {
+		if lsn <= a.lastAppliedLSN.Load() {
+			delete(a.nackedAt, lsn)
+		}
+	}
+	if len(lsns) == 0 {
+		return
+	}
+
+	log.Printf("%s: Detected gap, sending Nack for LSNs %v (lastApplied=%d, highestSeen=%d)\n",
+		role(a.isPrimary), lsns, a.lastAppliedLSN.Load(), a.highestSeenLSN)
+	ctx.Request(a.primaryPID, &messages.Nack{LastApplied: a.lastAppliedLSN.Load(), Lsns: lsns, Term: a.term.Load()})
+}
+
+// handleNack resends the requested entries from Log (plus Commit for those already applied),
+// or falls back to a state transfer when an entry has been compacted away
+func (a *Actor) handleNack(ctx actor.Context, msg *messages.Nack) {
+	if !a.acceptReplicaTerm(ctx, msg.Term) || !a.isPrimary {
+		return
+	}
+	log.Printf("Primary: Received Nack for LSNs %v from %s\n", msg.Lsns, ctx.Sender().String())
+	lastApplied := a.lastAppliedLSN.Load()
+
+	a.Mu.Lock()
+	resend := make([]*Request, 0, len(msg.Lsns))
+	compacted := false
+	for _, lsn := range msg.Lsns {
+		if req, exists := a.Log[lsn]; exists {
+			resend = append(resend, req)
+		} else if lsn <= a.snapshotLSN.Load() {
+			compacted = true
+		}
+	}
+	a.Mu.Unlock()
+
+	if compacted {
+		a.sendStateTransfer(ctx, ctx.Sender(), msg.LastApplied)
+		return
+	}
+
+	term := a.term.Load()
+	for _, req := range resend {
+		switch req.Type {
+		case "READ":
+			ctx.Request(ctx.Sender(), &messages.Read{Lsn: req.LSN, Request: req.Key, Term: term})
+		case "NOOP":
+			ctx.Request(ctx.Sender(), &messages.Abort{Lsn: req.LSN, Term: term})
+		case "CONFIG":
+			ctx.Request(ctx.Sender(), configChange(req, term))
+			if req.LSN <= lastApplied {
+				ctx.Request(ctx.Sender(), &messages.Commit{Lsn: req.LSN, Term: term})
+			}
+		default:
+			ctx.Request(ctx.Sender(), &messages.Write{Type: req.Type, Lsn: req.LSN, Key: req.Key, Val: req.Val, Term: term, Expect: req.Expect, Txn: txnToWire(req.Ops)})
+			if req.LSN <= lastApplied {
+				ctx.Request(ctx.Sender(), &messages.Commit{Lsn: req.LSN, Term: term})
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Replication engines selected with -replicator
+const (
+	replicatorPrimaryBackup = "primary-backup" // Actor: primary fan-out or chain, with backups promoted by election
+	replicatorRaft          = "raft"           // RaftNode: leader election, log matching and commit index per Raft
+)
+
+// Replicator is the replication engine behind the HTTP API. The Server registers each client
+// request as pending and hands it over; the engine answers it through Server.CompletePendingRequest.
+type Replicator interface {
+	Write(req *Request)
+	Read(req *Request)                          // Logged on the primary, served from the local store elsewhere
+	IsPrimary() bool                            // Whether this node accepts writes
+	AppliedLSN() int64                          // Highest LSN applied to the local store
+	PrimaryHTTP() string                        // Primary's client-facing host:port, "" when unknown
+	ReadIndex() (int64, error)                  // LSN a linearizable read on this node must wait for
+	Durability(mode string) (Durability, error) // Resolves an X-Durability header, "" meaning the default
+	MemberStatus() *MemberStatus
+}
+
+// membershipChanger is implemented by engines that support POST /admin/members
+type membershipChanger interface {
+	ChangeMembership(req *Request, op, address string)
+}
+
+// healthReporter is implemented by engines that support GET /admin/health
+type healthReporter interface {
+	HealthStatus() *HealthStatus
+}
+
+// consistencyReporter is implemented by engines that support GET /admin/consistency
+type consistencyReporter interface {
+	ConsistencyStatus() *ConsistencyStatus
+}
+
+// ParseReplicator validates the -replicator flag
+func ParseReplicator(engine string) (string, error) {
+	switch engine {
+	case replicatorPrimaryBackup, replicatorRaft:
+		return engine, nil
+	}
+	return "", fmt.Errorf("unknown replicator %q (use primary-backup or raft)", engine)
+}
+
+// Write replicates a client write from the primary
+func (a *Actor) Write(req *Request) {
+	a.write(req)
+}
+
+// Read serves a client read: through the log or lease on the primary, from the store on a backup
+func (a *Actor) Read(req *Request) {
+	a.read(req)
+}
+
+func (a *Actor) IsPrimary() bool {
+	return a.isPrimary
+}
+
+func (a *Actor) AppliedLSN() int64 {
+	return a.lastAppliedLSN.Load()
+}
+
+func (a *Actor) PrimaryHTTP() string {
+	return a.primaryHTTP
+}
+
+// ReadIndex is the primary's confirmed commit index, or what the committing tail of a chain already applied
+func (a *Actor) ReadIndex() (int64, error) {
+	if a.isCommittingTail() {
+		return a.lastAppliedLSN.Load(), nil
+	}
+	return a.requestReadIndex()
+}
+
+// Durability picks the mode for a request's X-Durability header, defaulting to the cluster's
+func (a *Actor) Durability(mode string) (Durability, error) {
+	if mode == "" {
+		return a.durability, nil
+	}
+	if a.chainMode() {
+		return "", fmt.Errorf("%s is not supported with chain replication, which always commits at the tail", durabilityHeader)
+	}
+	return ParseDurability(mode)
+}
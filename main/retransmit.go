@@ -0,0 +1,164 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	retransmitMaxBackoff  = 5 * time.Second
+	retransmitMaxAttempts = 20 // Give up on a replica after this many resends of one LSN
+)
+
+// retransmitTick is a local message that drives the primary's retransmit timers
+type retransmitTick struct{}
+
+// retransmitEntry tracks which replicas still owe an ack for one LSN
+type retransmitEntry struct {
+	accept      proto.Message    // Write, Read or Abort sent for the LSN
+	commit      *messages.Commit // Set once the primary applied a WRITE
+	acked       map[string]bool  // Replicas that acked the accept
+	commitAcked map[string]bool  // Replicas that acked the Commit
+	attempts    int
+	backoff     time.Duration
+	nextRetry   time.Time
+}
+
+// startRetransmitTimer ticks the actor so overdue LSNs are resent
+func (a *Actor) startRetransmitTimer() {
+	if a.retransmitInterval <= 0 {
+		return
+	}
+	self := a.self
+	go func() {
+		ticker := time.NewTicker(a.retransmitInterval / 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.system.Root.Send(self, &retransmitTick{})
+		}
+	}()
+}
+
+// trackAccept arms the retransmit timer for a Write/Read just sent to every target
+func (a *Actor) trackAccept(lsn int64, accept proto.Message) {
+	if a.retransmitInterval <= 0 {
+		return
+	}
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	a.retransmits[lsn] = &retransmitEntry{
+		accept:      accept,
+		acked:       make(map[string]bool),
+		commitAcked: make(map[string]bool),
+		backoff:     a.retransmitInterval,
+		nextRetry:   time.Now().Add(a.retransmitInterval),
+	}
+}
+
+// trackCommit switches an LSN to waiting for Commit acks
+func (a *Actor) trackCommit(lsn int64, commit *messages.Commit) {
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	if entry, exists := a.retransmits[lsn]; exists {
+		entry.commit = commit
+	}
+}
+
+// recordReplicaAck marks a replica as done with the accept or Commit phase of an LSN
+func (a *Actor) recordReplicaAck(lsn int64, replica string, commit bool) {
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	entry, exists := a.retransmits[lsn]
+	if !exists {
+		return
+	}
+	if commit {
+		entry.commitAcked[replica] = true
+		entry.acked[replica] = true // A replica can only apply what it logged
+	} else {
+		entry.acked[replica] = true
+	}
+}
+
+// handleRetransmitTick resends overdue Write/Read/Commit messages to replicas that haven't acked
+// and forgets LSNs that every target has acknowledged
+func (a *Actor) handleRetransmitTick(ctx actor.Context) {
+	if !a.isPrimary {
+		return
+	}
+	targets := a.liveTargets() // Dead replicas are caught up by state transfer once they answer again
+	now := time.Now()
+
+	a.retransmitMu.Lock()
+	defer a.retransmitMu.Unlock()
+
+	for lsn, entry := range a.retransmits {
+		if a.retransmitDone(entry, targets) {
+			delete(a.retransmits, lsn)
+			continue
+		}
+		if now.Before(entry.nextRetry) {
+			continue
+		}
+		if entry.attempts >= retransmitMaxAttempts {
+			log.Printf("Primary: Giving up retransmitting LSN %d after %d attempts\n", lsn, entry.attempts)
+			delete(a.retransmits, lsn)
+			continue
+		}
+
+		for _, target := range targets {
+			replica := target.String()
+			if !entry.acked[replica] {
+				log.Printf("Primary: Retransmitting %T(LSN=%d) to %s (attempt %d)\n", entry.accept, lsn, replica, entry.attempts+1)
+				ctx.Request(target, entry.accept)
+			}
+			if entry.commit != nil && !entry.commitAcked[replica] {
+				log.Printf("Primary: Retransmitting Commit(LSN=%d) to %s (attempt %d)\n", lsn, replica, entry.attempts+1)
+				ctx.Request(target, entry.commit)
+			}
+		}
+
+		entry.attempts++
+		entry.backoff = min(entry.backoff*2, retransmitMaxBackoff)
+		entry.nextRetry = now.Add(entry.backoff)
+	}
+}
+
+// retransmitDone reports whether every target has acked everything it needs for an entry
+func (a *Actor) retransmitDone(entry *retransmitEntry, targets []*actor.PID) bool {
+	// Reads and Aborts have no Commit phase
+	singlePhase := false
+	switch entry.accept.(type) {
+	case *messages.Read, *messages.Abort:
+		singlePhase = true
+	}
+	if !singlePhase && entry.commit == nil {
+		return false // Still waiting for the primary to commit
+	}
+	for _, target := range targets {
+		replica := target.String()
+		if !entry.acked[replica] || (!singlePhase && !entry.commitAcked[replica]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ackCommit tells the primary a Commit was applied or queued so it stops retransmitting it
+func (a *Actor) ackCommit(ctx actor.Context, lsn int64) {
+	primary := ctx.Sender()
+	if primary == nil {
+		primary = a.primaryPID
+	}
+	if primary != nil {
+		ctx.Request(primary, &messages.Ack{Lsn: lsn, Commit: true, Term: a.term.Load(), AppliedLsn: a.lastAppliedLSN.Load()})
+	}
+}
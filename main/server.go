@@ -7,9 +7,13 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// requestTimeout bounds how long a client request waits for its LSN to be applied
+const requestTimeout = 30 * time.Second
+
 // HTTPRequest represents the JSON body for POST requests
 type HTTPRequest struct {
 	Val string `json:"val"`
@@ -17,50 +21,70 @@ type HTTPRequest struct {
 
 // HTTPResponse represents the response to clients
 type HTTPResponse struct {
-	Key   string `json:"key,omitempty"`
-	Value string `json:"value,omitempty"`
-	Error string `json:"error,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Durability string `json:"durability,omitempty"`
+	LSN        int64  `json:"lsn,omitempty"`     // Pass back as min_lsn to read at least this state from a backup
+	Version    int64  `json:"version,omitempty"` // Pass back as If-Match or expected_version to write only if unchanged
 }
 
 // PendingRequest tracks requests waiting for quorum
 type PendingRequest struct {
-	request   *Request
-	acks      int
-	respChan  chan *Response
-	startTime time.Time
-	committed bool
+	request    *Request
+	acks       int
+	ackedBy    map[string]bool // Replicas already counted, so retransmitted acks aren't double counted
+	durability Durability      // Acks required before the request commits
+	respChan   chan *Response
+	startTime  time.Time
+	committed  bool
+	recovered  bool // Re-replicated by a new primary; may already be committed, so never aborted
+	aborted    bool // Replaced by a no-op; only acks of the Abort count from then on
 }
 
 // Request represents an internal operation
 type Request struct {
-	Type string // "READ" or "WRITE"
-	Key  string
-	Val  string
-	LSN  int64
+	Type   string // "READ", "WRITE", "DELETE" or "TXN"
+	Key    string
+	Val    string
+	LSN    int64
+	Term   int64   // Term of the primary that logged the entry at LSN
+	Expect int64   // Version condition checked when a WRITE or DELETE applies, expectAny if unconditional
+	Ops    []TxnOp // Puts and deletes of a TXN, applied together
 }
 
 // Response represents the result of an operation
 type Response struct {
-	Success bool
-	Key     string
-	Value   string
-	Error   string
+	Success    bool
+	Key        string
+	Value      string
+	Error      string
+	Status     int        // HTTP status for failures (defaults to 500)
+	Durability Durability // Mode the request was committed with, empty for local backup reads
+	LSN        int64      // Committed LSN, or the applied LSN a local read observed
+	Version    int64      // Version of the key written or read, or the current one when a condition failed
 }
 
 // Server manages HTTP endpoints and pending requests
 type Server struct {
-	actor         *Actor
+	replicator    Replicator
 	pendingReqs   map[int64]*PendingRequest
 	committedReqs []int64
 	pendingMu     sync.Mutex
 	port          int
+	tempLSN       atomic.Int64             // Source of unique negative keys for requests not yet assigned an LSN
+	minLSNWait    time.Duration            // How long a backup read waits for min_lsn or a read index before redirecting
+	shards        atomic.Pointer[ShardMap] // nil unless keys are partitioned across replica groups
+	backupWrites  string                   // forward or redirect client writes while a backup
 }
 
-func NewServer(actor *Actor, port int) *Server {
+func NewServer(replicator Replicator, port int, minLSNWait time.Duration) *Server {
 	return &Server{
-		actor:       actor,
-		pendingReqs: make(map[int64]*PendingRequest),
-		port:        port,
+		replicator:   replicator,
+		pendingReqs:  make(map[int64]*PendingRequest),
+		port:         port,
+		minLSNWait:   minLSNWait,
+		backupWrites: backupWritesForward,
 	}
 }
 
@@ -94,23 +118,42 @@ func (s *Server) routeHandler(w http.ResponseWriter, r *http.Request) {
 		path = path[1:]
 	}
 
+	if strings.HasPrefix(path, "admin/") {
+		s.handleAdmin(w, r, strings.TrimPrefix(path, "admin/"))
+		return
+	}
+
+	if path == "txn" && r.Method == http.MethodPost {
+		s.handleTxn(w, r)
+		return
+	}
+
+	// Send keys owned by another replica group to its primary
+	if key, _, _ := strings.Cut(path, "/"); !s.routeToShard(w, r, key) {
+		return
+	}
+
 	// Route based on HTTP method
 	switch r.Method {
 	case http.MethodGet:
 		// GET: /key
 		log.Printf("Received GET request with path: %s", path)
 		s.handleRead(w, r, path)
-	case http.MethodPost:
-		// POST: /key/value
-		log.Printf("Received POST request with path: %s", path)
+	case http.MethodPost, http.MethodPut:
+		// POST or PUT: /key/value
+		log.Printf("Received %s request with path: %s", r.Method, path)
 		parts := strings.Split(path, "/")
 		if len(parts) < 2 {
-			s.sendError(w, "POST requests require format: /key/value", http.StatusBadRequest)
+			s.sendError(w, r.Method+" requests require format: /key/value", http.StatusBadRequest)
 			return
 		}
 		key := parts[0]
 		value := strings.Join(parts[1:], "/") // In case value contains slashes
 		s.handleWrite(w, r, key, value)
+	case http.MethodDelete:
+		// DELETE: /key
+		log.Printf("Received DELETE request with path: %s", path)
+		s.handleDelete(w, r, path)
 	default:
 		s.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -125,15 +168,36 @@ func (s *Server) handleRead(w http.ResponseWriter, r *http.Request, key string)
 		return
 	}
 
-	req := &Request{Type: "READ", Key: key, LSN: -1}
-	respChan := s.RegisterPendingRequest(-1, req) // Will be updated with actual LSN in read()
+	if param := r.URL.Query().Get("at_lsn"); param != "" {
+		s.handleReadAt(w, r, key, param)
+		return
+	}
+
+	if !s.awaitMinLSN(w, r, key) {
+		return
+	}
+	if r.URL.Query().Get("consistency") == "linearizable" && !s.replicator.IsPrimary() && !s.awaitReadIndex(w, r, key) {
+		return
+	}
+
+	durability, err := s.requestDurability(r)
+	if err != nil {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.replicator.IsPrimary() {
+		durability = "" // Backups read their own store
+	}
 
-	go s.actor.read(req)
+	req := &Request{Type: "READ", Key: key, LSN: s.NextTempLSN()}
+	respChan := s.RegisterPendingRequest(req.LSN, req, durability) // Will be updated with actual LSN in read()
+
+	go s.replicator.Read(req)
 
 	select {
 	case resp := <-respChan:
 		s.sendResponse(w, resp)
-	case <-time.After(30 * time.Second):
+	case <-time.After(requestTimeout):
 		s.sendError(w, "Request timeout", http.StatusRequestTimeout)
 	}
 }
@@ -142,42 +206,71 @@ func (s *Server) handleRead(w http.ResponseWriter, r *http.Request, key string)
 func (s *Server) handleWrite(w http.ResponseWriter, r *http.Request, key string, val string) {
 	log.Printf("Handling WRITE request for key: %s, value: %s", key, val)
 
-	// Only primary can accept writes
-	if !s.actor.isPrimary {
-		s.sendError(w, "Only primary can accept writes", http.StatusForbidden)
+	if val == "" {
+		s.sendError(w, "Value is required", http.StatusBadRequest)
 		return
 	}
 
-	if val == "" {
-		s.sendError(w, "Value is required", http.StatusBadRequest)
+	expect, err := parseExpect(r)
+	if err != nil {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &Request{Type: "WRITE", Key: key, Val: val, Expect: expect}
+
+	// Only primary can accept writes, backups pass them on
+	if !s.replicator.IsPrimary() {
+		s.handleBackupWrite(w, r, req)
 		return
 	}
 
-	// Create request and get response channel
-	req := &Request{Type: "WRITE", Key: key, Val: val, LSN: -1}
-	respChan := s.RegisterPendingRequest(-1, req) // Will be updated with actual LSN in write()
+	s.sendResponse(w, s.submitWrite(req, r.Header.Get(durabilityHeader)))
+}
+
+// submitWrite replicates a WRITE, DELETE or TXN on the primary and waits for it to commit. mode
+// is the request's X-Durability header, "" for the cluster's default.
+func (s *Server) submitWrite(req *Request, mode string) *Response {
+	durability, err := s.replicator.Durability(mode)
+	if err != nil {
+		return &Response{Success: false, Key: req.Key, Error: err.Error(), Status: http.StatusBadRequest}
+	}
 
-	go s.actor.write(req)
+	// Get response channel
+	req.LSN = s.NextTempLSN()
+	respChan := s.RegisterPendingRequest(req.LSN, req, durability) // Will be updated with actual LSN in write()
+
+	go s.replicator.Write(req)
 
 	// Wait for response with timeout
 	select {
 	case resp := <-respChan:
-		s.sendResponse(w, resp)
-	case <-time.After(30 * time.Second):
-		s.sendError(w, "Request timeout", http.StatusRequestTimeout)
+		return resp
+	case <-time.After(requestTimeout):
+		return &Response{Success: false, Key: req.Key, Error: "Request timeout", Status: http.StatusRequestTimeout}
 	}
 }
 
 // sendResponse sends a successful response to the client
 func (s *Server) sendResponse(w http.ResponseWriter, resp *Response) {
+	if resp.Version > 0 {
+		w.Header().Set("ETag", fmt.Sprintf("\"%d\"", resp.Version))
+	}
 	if !resp.Success {
-		s.sendError(w, resp.Error, http.StatusInternalServerError)
+		status := resp.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		s.sendError(w, resp.Error, status)
 		return
 	}
 
 	httpResp := HTTPResponse{
-		Key:   resp.Key,
-		Value: resp.Value,
+		Key:        resp.Key,
+		Value:      resp.Value,
+		Durability: string(resp.Durability),
+		LSN:        resp.LSN,
+		Version:    resp.Version,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -196,23 +289,41 @@ func (s *Server) sendError(w http.ResponseWriter, message string, statusCode int
 	json.NewEncoder(w).Encode(httpResp)
 }
 
+// NextTempLSN returns a unique negative LSN so concurrent requests don't share a pending slot
+// before the actor assigns their real LSN
+func (s *Server) NextTempLSN() int64 {
+	return s.tempLSN.Add(-1)
+}
+
 // RegisterPendingRequest tracks a request waiting for quorum
-func (s *Server) RegisterPendingRequest(lsn int64, req *Request) chan *Response {
+func (s *Server) RegisterPendingRequest(lsn int64, req *Request, durability Durability) chan *Response {
 	s.pendingMu.Lock()
 	defer s.pendingMu.Unlock()
 
 	pending := &PendingRequest{
-		request:   req,
-		acks:      1, // Primary counts as first ack
-		respChan:  make(chan *Response, 1),
-		startTime: time.Now(),
-		committed: false,
+		request:    req,
+		acks:       1, // Primary counts as first ack
+		ackedBy:    make(map[string]bool),
+		durability: durability,
+		respChan:   make(chan *Response, 1),
+		startTime:  time.Now(),
+		committed:  false,
 	}
 
 	s.pendingReqs[lsn] = pending
 	return pending.respChan
 }
 
+// RegisterRecoveredRequest tracks an entry a new primary took over from an earlier term. No client
+// waits for it, and it is never aborted since a quorum may already have logged it.
+func (s *Server) RegisterRecoveredRequest(lsn int64, req *Request, durability Durability) {
+	s.RegisterPendingRequest(lsn, req, durability)
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.pendingReqs[lsn].recovered = true
+}
+
 // UpdatePendingRequestLSN moves a pending request from temporary LSN to actual LSN
 func (s *Server) UpdatePendingRequestLSN(tempLSN, actualLSN int64, req *Request) {
 	s.pendingMu.Lock()
@@ -225,29 +336,85 @@ func (s *Server) UpdatePendingRequestLSN(tempLSN, actualLSN int64, req *Request)
 		s.pendingReqs[actualLSN] = pending
 	} else {
 		// If not found, create new entry
+		durability, _ := s.replicator.Durability("")
 		pending := &PendingRequest{
-			request:   req,
-			acks:      1, // Primary counts as first ack
-			respChan:  make(chan *Response, 1),
-			startTime: time.Now(),
-			committed: false,
+			request:    req,
+			acks:       1, // Primary counts as first ack
+			ackedBy:    make(map[string]bool),
+			durability: durability,
+			respChan:   make(chan *Response, 1),
+			startTime:  time.Now(),
+			committed:  false,
 		}
 		s.pendingReqs[actualLSN] = pending
 	}
 }
 
-// RecordAck increments the ack count for a pending request
-func (s *Server) RecordAck(lsn int64) (int, bool) {
+// RecordAck increments the ack count for a pending request, once per replica, and reports
+// whether the request's durability mode is now satisfied out of replicas
+func (s *Server) RecordAck(lsn int64, replica string, replicas int) (bool, bool) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	pending, exists := s.pendingReqs[lsn]
+	if !exists {
+		return false, false
+	}
+	if pending.aborted {
+		return false, true // A late ack of the aborted entry must not commit it
+	}
+
+	if !pending.ackedBy[replica] {
+		pending.ackedBy[replica] = true
+		pending.acks++
+	}
+	return pending.acks >= pending.durability.requiredAcks(replicas), true
+}
+
+// DurabilityReached reports whether a pending request already has the acks its mode requires
+func (s *Server) DurabilityReached(lsn int64, replicas int) (bool, bool) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	pending, exists := s.pendingReqs[lsn]
+	if !exists || pending.aborted {
+		return false, exists
+	}
+	return pending.acks >= pending.durability.requiredAcks(replicas), true
+}
+
+// AbortPendingRequest restarts a request's ack count for the no-op that replaced it, and reports
+// whether the primary's own log of the no-op is already a quorum out of replicas
+func (s *Server) AbortPendingRequest(lsn int64, replicas int) (bool, bool) {
 	s.pendingMu.Lock()
 	defer s.pendingMu.Unlock()
 
 	pending, exists := s.pendingReqs[lsn]
 	if !exists {
-		return 0, false
+		return false, false
+	}
+	pending.aborted = true
+	pending.acks = 1 // Primary counts as first ack
+	pending.ackedBy = make(map[string]bool)
+	return pending.acks >= quorumSize(replicas), true
+}
+
+// RecordAbortAck counts a replica's ack of an Abort, once per replica, and reports whether a
+// quorum out of replicas has now logged the no-op, whatever durability the request asked for
+func (s *Server) RecordAbortAck(lsn int64, replica string, replicas int) (bool, bool) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	pending, exists := s.pendingReqs[lsn]
+	if !exists || !pending.aborted {
+		return false, false
 	}
 
-	pending.acks++
-	return pending.acks, true
+	if !pending.ackedBy[replica] {
+		pending.ackedBy[replica] = true
+		pending.acks++
+	}
+	return pending.acks >= quorumSize(replicas), true
 }
 
 // CompletePendingRequest marks a request as complete and sends response
@@ -262,6 +429,12 @@ func (s *Server) CompletePendingRequest(lsn int64, resp *Response) {
 
 	if !pending.committed {
 		pending.committed = true
+		if resp.Success && resp.Durability == "" {
+			resp.Durability = pending.durability
+		}
+		if resp.Success && resp.LSN == 0 && lsn > 0 {
+			resp.LSN = lsn
+		}
 		pending.respChan <- resp
 		close(pending.respChan)
 	}
@@ -270,6 +443,21 @@ func (s *Server) CompletePendingRequest(lsn int64, resp *Response) {
 	delete(s.pendingReqs, lsn)
 }
 
+// FailPendingRequests completes every outstanding request with resp
+func (s *Server) FailPendingRequests(resp *Response) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	for lsn, pending := range s.pendingReqs {
+		if !pending.committed {
+			pending.committed = true
+			pending.respChan <- resp
+			close(pending.respChan)
+		}
+		delete(s.pendingReqs, lsn)
+	}
+}
+
 // GetPendingRequest retrieves a pending request by LSN
 func (s *Server) GetPendingRequest(lsn int64) (*PendingRequest, bool) {
 	s.pendingMu.Lock()
@@ -278,3 +466,32 @@ func (s *Server) GetPendingRequest(lsn int64) (*PendingRequest, bool) {
 	pending, exists := s.pendingReqs[lsn]
 	return pending, exists
 }
+
+// TruncateCommitted drops committed LSNs at or below a snapshot LSN
+func (s *Server) TruncateCommitted(lsn int64) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	kept := s.committedReqs[:0]
+	for _, committed := range s.committedReqs {
+		if committed > lsn {
+			kept = append(kept, committed)
+		}
+	}
+	s.committedReqs = kept
+}
+
+// ExpiredWithoutQuorum lists unapplied LSNs whose request has waited longer than deadline
+// without the acks its durability mode requires out of replicas
+func (s *Server) ExpiredWithoutQuorum(deadline time.Duration, replicas int, lastApplied int64) []int64 {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	expired := make([]int64, 0)
+	for lsn, pending := range s.pendingReqs {
+		if lsn > lastApplied && !pending.committed && !pending.recovered && !pending.aborted && pending.acks < pending.durability.requiredAcks(replicas) && time.Since(pending.startTime) > deadline {
+			expired = append(expired, lsn)
+		}
+	}
+	return expired
+}
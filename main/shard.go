@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"distributed/messages"
+)
+
+// shardVirtualNodes is how many points each shard gets on the hash ring, which evens out
+// the share of keys each one owns
+const shardVirtualNodes = 64
+
+const (
+	shardAnnounceRetry    = time.Second // How often a new primary retries groups that missed its announcement
+	shardAnnounceAttempts = 30          // Rounds before it gives up on a group that stays unreachable
+)
+
+// shardClient posts shard announcements to the other groups
+var shardClient = &http.Client{Timeout: time.Second}
+
+// Shard is one independent primary/backup group in the shard map
+type Shard struct {
+	ID      string `json:"id"`
+	Primary string `json:"primary"` // Client-facing host:port of the group's primary
+	Term    int64  `json:"term"`    // Term the group announced Primary in, 0 if it came from -shards
+}
+
+// ringPoint is one virtual node: keys hashing up to it belong to shards[shard]
+type ringPoint struct {
+	hash  uint32
+	shard int
+}
+
+// ShardMap partitions keys across shards by consistent hashing. It is immutable once built,
+// so the Server swaps in a new one rather than locking it.
+type ShardMap struct {
+	Self   string  `json:"self"` // Shard this node belongs to
+	Shards []Shard `json:"shards"`
+	ring   []ringPoint
+}
+
+// NewShardMap builds the hash ring for shards, of which self is this node's own
+func NewShardMap(self string, shards []Shard) (*ShardMap, error) {
+	m := &ShardMap{Self: self, Shards: shards}
+	seen := make(map[string]bool, len(shards))
+	for i, shard := range shards {
+		if shard.ID == "" || seen[shard.ID] {
+			return nil, fmt.Errorf("shard ids must be unique and non-empty, got %q", shard.ID)
+		}
+		seen[shard.ID] = true
+		for v := 0; v < shardVirtualNodes; v++ {
+			m.ring = append(m.ring, ringPoint{hash: hashKey(fmt.Sprintf("%s#%d", shard.ID, v)), shard: i})
+		}
+	}
+	if !seen[self] {
+		return nil, fmt.Errorf("shard %q is not in the shard map", self)
+	}
+	sort.Slice(m.ring, func(i, j int) bool { return m.ring[i].hash < m.ring[j].hash })
+	return m, nil
+}
+
+// ParseShardMap parses the -shards flag, id=host:port pairs naming each group's primary HTTP address
+func ParseShardMap(list, self string) (*ShardMap, error) {
+	shards := make([]Shard, 0)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, primary, found := strings.Cut(entry, "=")
+		if !found || primary == "" {
+			return nil, fmt.Errorf("shard %q must be id=host:port", entry)
+		}
+		shards = append(shards, Shard{ID: id, Primary: primary})
+	}
+	return NewShardMap(self, shards)
+}
+
+// hashKey places a key or virtual node on the ring. md5 spreads similar keys like key1, key2
+// far more evenly than the faster non-cryptographic hashes.
+func hashKey(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// Owner is the shard whose ring point comes first at or after the key's hash
+func (m *ShardMap) Owner(key string) Shard {
+	hash := hashKey(key)
+	i := sort.Search(len(m.ring), func(i int) bool { return m.ring[i].hash >= hash })
+	if i == len(m.ring) {
+		i = 0 // Wrap around the ring
+	}
+	return m.Shards[m.ring[i].shard]
+}
+
+// Owns reports whether key belongs to this node's shard
+func (m *ShardMap) Owns(key string) bool {
+	return m.Owner(key).ID == m.Self
+}
+
+// merge is the map with every shard that learned announces in a later term replaced by that
+// announcement. The shards keep their ring points, so it returns m itself if nothing is newer.
+func (m *ShardMap) merge(learned []Shard) *ShardMap {
+	var shards []Shard
+	for _, shard := range learned {
+		for i := range m.Shards {
+			if m.Shards[i].ID != shard.ID || shard.Term <= m.Shards[i].Term {
+				continue
+			}
+			if shards == nil {
+				shards = append([]Shard(nil), m.Shards...)
+			}
+			if shard.Term > shards[i].Term {
+				shards[i] = shard
+			}
+		}
+	}
+	if shards == nil {
+		return m
+	}
+	return &ShardMap{Self: m.Self, Shards: shards, ring: m.ring}
+}
+
+// shardsToWire converts the shard map for a Membership broadcast
+func shardsToWire(m *ShardMap) (string, []*messages.Shard) {
+	if m == nil {
+		return "", nil
+	}
+	shards := make([]*messages.Shard, 0, len(m.Shards))
+	for _, shard := range m.Shards {
+		shards = append(shards, &messages.Shard{Id: shard.ID, PrimaryHttp: shard.Primary, Term: shard.Term})
+	}
+	return m.Self, shards
+}
+
+// shardListFromWire converts the shards of a Membership or AppendEntries message
+func shardListFromWire(wire []*messages.Shard) []Shard {
+	shards := make([]Shard, 0, len(wire))
+	for _, shard := range wire {
+		shards = append(shards, Shard{ID: shard.Id, Primary: shard.PrimaryHttp, Term: shard.Term})
+	}
+	return shards
+}
+
+// shardsFromWire rebuilds the shard map a primary broadcast, nil when it isn't sharded
+func shardsFromWire(self string, wire []*messages.Shard) (*ShardMap, error) {
+	if self == "" {
+		return nil, nil
+	}
+	return NewShardMap(self, shardListFromWire(wire))
+}
+
+// SetShardMap replaces the shard map used to route keys, nil serving every key locally
+func (s *Server) SetShardMap(m *ShardMap) {
+	s.shards.Store(m)
+}
+
+// learnShards adopts the shards announced in a later term than the ones this node routes by,
+// and reports whether there were any
+func (s *Server) learnShards(learned []Shard) bool {
+	for {
+		m := s.shards.Load()
+		if m == nil {
+			return false
+		}
+		merged := m.merge(learned)
+		if merged == m {
+			return false
+		}
+		if s.shards.CompareAndSwap(m, merged) {
+			for i, shard := range merged.Shards {
+				if shard != m.Shards[i] {
+					log.Printf("Shard %s now has primary %s (term %d)", shard.ID, shard.Primary, shard.Term)
+				}
+			}
+			return true
+		}
+	}
+}
+
+// AnnounceShardPrimary points this node's shard at primary, elected in term, and tells the other
+// groups so their redirects follow the failover. It does nothing when keys aren't sharded.
+func (s *Server) AnnounceShardPrimary(primary string, term int64) {
+	m := s.shards.Load()
+	if m == nil {
+		return
+	}
+	own := Shard{ID: m.Self, Primary: primary, Term: term}
+	s.learnShards([]Shard{own})
+	go s.announceShard(own)
+}
+
+// announceShard posts own to every other group's primary until each has taken it. The map is
+// re-read every round, since a group that failed over as well announces its new primary to us.
+func (s *Server) announceShard(own Shard) {
+	body, err := json.Marshal(own)
+	if err != nil {
+		log.Printf("Failed to encode shard announcement: %v", err)
+		return
+	}
+	pending := make(map[string]bool)
+	for _, shard := range s.shards.Load().Shards {
+		if shard.ID != own.ID {
+			pending[shard.ID] = true
+		}
+	}
+	for attempt := 0; attempt < shardAnnounceAttempts && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(shardAnnounceRetry)
+		}
+		for _, shard := range s.shards.Load().Shards {
+			if shard.ID == own.ID && shard.Term > own.Term {
+				return // A later primary of this group announces itself
+			}
+			if !pending[shard.ID] {
+				continue
+			}
+			resp, err := shardClient.Post("http://"+shard.Primary+"/admin/shards", "application/json", bytes.NewReader(body))
+			if err != nil {
+				log.Printf("Failed to announce shard %s primary to shard %s at %s: %v", own.ID, shard.ID, shard.Primary, err)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				log.Printf("Shard %s at %s refused the announcement of shard %s: %s", shard.ID, shard.Primary, own.ID, resp.Status)
+				continue
+			}
+			delete(pending, shard.ID)
+		}
+	}
+	if len(pending) > 0 {
+		log.Printf("Gave up announcing shard %s primary %s to %d shards", own.ID, own.Primary, len(pending))
+	}
+}
+
+// shardListener is implemented by engines that pass an updated shard map on to the rest of their group
+type shardListener interface {
+	ShardsChanged()
+}
+
+// handleShardAnnouncement serves POST /admin/shards, another group announcing its new primary.
+// A backup redirects it to its primary, so announcements sent to a demoted primary still land.
+func (s *Server) handleShardAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var shard Shard
+	if err := json.NewDecoder(r.Body).Decode(&shard); err != nil || shard.ID == "" || shard.Primary == "" {
+		s.sendError(w, "Shard announcements require a JSON body with id, primary and term", http.StatusBadRequest)
+		return
+	}
+	if s.shards.Load() == nil {
+		s.sendError(w, "Sharding is not enabled", http.StatusNotFound)
+		return
+	}
+	learned := s.learnShards([]Shard{shard})
+	if !s.replicator.IsPrimary() {
+		// Pass it on to the primary, which hands it to the rest of the group
+		s.redirectToPrimary(w, r, "admin/shards", fmt.Sprintf("Not the primary of shard %s", s.shards.Load().Self))
+		return
+	}
+	if listener, ok := s.replicator.(shardListener); ok && learned {
+		listener.ShardsChanged()
+	}
+	status, _ := s.shardStatus("")
+	s.sendJSON(w, status)
+}
+
+// routeToShard redirects a request for a key another shard owns to that shard's primary.
+// It returns false once it has answered the client itself.
+func (s *Server) routeToShard(w http.ResponseWriter, r *http.Request, key string) bool {
+	m := s.shards.Load()
+	if m == nil || m.Owns(key) {
+		return true
+	}
+	owner := m.Owner(key)
+	target := url.URL{Scheme: "http", Host: owner.Primary, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	log.Printf("Key %s belongs to shard %s, redirecting to %s", key, owner.ID, target.String())
+	http.Redirect(w, r, target.String(), http.StatusTemporaryRedirect)
+	return false
+}
+
+// ShardStatus is the JSON body returned by GET /admin/shards
+type ShardStatus struct {
+	*ShardMap
+	Key   string `json:"key,omitempty"`
+	Owner string `json:"owner,omitempty"` // Shard owning Key
+}
+
+// shardStatus reports the shard map, and which shard owns key when one is given
+func (s *Server) shardStatus(key string) (*ShardStatus, bool) {
+	m := s.shards.Load()
+	if m == nil {
+		return nil, false
+	}
+	status := &ShardStatus{ShardMap: m, Key: key}
+	if key != "" {
+		status.Owner = m.Owner(key).ID
+	}
+	return status, true
+}
+
+// learnShards adopts the shard map from a primary's Membership broadcast, so a backup routes keys
+// the same way and keeps it if elected primary. Shards it already knows from a later term are kept.
+func (a *Actor) learnShards(self string, wire []*messages.Shard) {
+	m, err := shardsFromWire(self, wire)
+	if err != nil {
+		log.Printf("%s: Ignoring invalid shard map from primary: %v\n", role(a.isPrimary), err)
+		return
+	}
+	if current := a.Server.shards.Load(); current != nil && m != nil && current.Self == m.Self {
+		a.Server.learnShards(m.Shards)
+		return
+	}
+	a.Server.SetShardMap(m)
+}
+
+// shardsChanged is a local message that makes the primary pass an updated shard map to its backups
+type shardsChanged struct{}
+
+// ShardsChanged rebroadcasts Membership once another group's new primary was learned
+func (a *Actor) ShardsChanged() {
+	a.system.Root.Send(a.self, &shardsChanged{})
+}
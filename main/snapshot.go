@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"distributed/messages"
+)
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".snap"
+)
+
+// maybeSnapshot compacts once snapshotEvery LSNs have been applied since the last snapshot
+func (a *Actor) maybeSnapshot() {
+	if a.snapshotEvery <= 0 {
+		return
+	}
+	if a.lastAppliedLSN.Load()-a.snapshotLSN.Load() < a.snapshotEvery {
+		return
+	}
+	if !a.snapshotting.CompareAndSwap(false, true) {
+		return // Previous snapshot is still being written
+	}
+	a.takeSnapshot()
+}
+
+// takeSnapshot captures store at lastAppliedLSN and truncates every log entry at or below it.
+// The WAL is rotated while Mu is held so all later commits land in the new segment; the
+// snapshot file is written and the sealed segments removed in the background.
+func (a *Actor) takeSnapshot() {
+	a.Mu.Lock()
+	lsn := a.lastAppliedLSN.Load()
+	store := make(map[string]string, len(a.store))
+	versions := make(map[string]int64, len(a.versions))
+	for key, val := range a.store {
+		store[key] = val
+		versions[key] = a.versions[key]
+	}
+	config := a.config
+	retained := make([]*Request, 0)
+	for entry, req := range a.Log {
+		if entry <= lsn {
+			delete(a.Log, entry)
+		} else {
+			retained = append(retained, req)
+		}
+	}
+	compactTombstones(a.tombstones, lsn)
+	sealed, err := a.wal.Rotate(retained)
+	a.Mu.Unlock()
+
+	a.snapshotLSN.Store(lsn)
+	a.Server.TruncateCommitted(lsn)
+	log.Printf("%s: Snapshot at LSN %d (%d keys), truncated log (%d entries retained)\n",
+		role(a.isPrimary), lsn, len(store), len(retained))
+
+	if err != nil {
+		log.Printf("%s: Failed to rotate WAL for snapshot: %v\n", role(a.isPrimary), err)
+		a.snapshotting.Store(false)
+		return
+	}
+	go func() {
+		defer a.snapshotting.Store(false)
+		if err := a.wal.Compact(sealed, lsn, store, versions, config); err != nil {
+			log.Printf("%s: Failed to compact WAL at LSN %d: %v\n", role(a.isPrimary), lsn, err)
+		}
+	}()
+}
+
+// Rotate starts a new segment and re-logs the retained (uncommitted) entries into it.
+// It returns the first segment that must be kept once a snapshot is durable.
+func (w *WAL) Rotate(retained []*Request) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rotate(); err != nil {
+		return 0, err
+	}
+	sort.Slice(retained, func(i, j int) bool { return retained[i].LSN < retained[j].LSN })
+	for _, req := range retained {
+		if err := w.appendLocked(entryRecord(req)); err != nil {
+			return 0, err
+		}
+	}
+	return w.segment, nil
+}
+
+// Compact durably writes a snapshot at lsn, then removes segments before sealed and every other snapshot.
+// The snapshot keeps the latest committed config change, whose entry the segments held.
+func (w *WAL) Compact(sealed int, lsn int64, store map[string]string, versions map[string]int64, config *Request) error {
+	if err := writeSnapshotFile(w.opts.Dir, lsn, store, versions, config); err != nil {
+		return err
+	}
+
+	segments, err := walSegments(w.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if segment < sealed {
+			if err := os.Remove(w.segmentPath(segment)); err != nil {
+				return err
+			}
+		}
+	}
+
+	snapshots, err := snapshotFiles(w.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		// A RESET can move the applied LSN back, so a higher snapshot may hold a diverged store
+		if snapshot != lsn {
+			if err := os.Remove(snapshotPath(w.opts.Dir, snapshot)); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("WAL: Compacted at LSN %d (removed segments before %d)\n", lsn, sealed)
+	return nil
+}
+
+// writeSnapshotFile writes the snapshot to a temp file and renames it into place once synced
+func writeSnapshotFile(dir string, lsn int64, store map[string]string, versions map[string]int64, config *Request) error {
+	record := &messages.WalRecord{Kind: walSnapshot, Lsn: lsn, Store: store, Versions: versions}
+	if config != nil {
+		record.Config = entryRecord(config)
+	}
+	buf, err := encodeWALRecord(record)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(snapshotPath(dir, lsn), buf)
+}
+
+// writeFileAtomic writes data to a temp file and renames it over path once synced
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename survives a crash
+	dirFile, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dirFile.Close()
+	return dirFile.Sync()
+}
+
+// readSnapshot loads the newest snapshot in dir, if any, as the replay starting point
+func (r *walReplay) readSnapshot(dir string) error {
+	snapshots, err := snapshotFiles(dir)
+	if err != nil || len(snapshots) == 0 {
+		return err
+	}
+
+	path := snapshotPath(dir, snapshots[len(snapshots)-1])
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	record, _, err := readWALRecord(file, make([]byte, walHeaderSize))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	r.apply(record)
+	log.Printf("WAL: Loaded snapshot %s (LSN=%d, keys=%d)\n", path, record.Lsn, len(record.Store))
+	return nil
+}
+
+func snapshotPath(dir string, lsn int64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, lsn, snapshotSuffix))
+}
+
+// snapshotFiles lists snapshot LSNs in dir in ascending order
+func snapshotFiles(dir string) ([]int64, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, snapshotPrefix+"*"+snapshotSuffix))
+	if err != nil {
+		return nil, err
+	}
+	lsns := make([]int64, 0, len(matches))
+	for _, match := range matches {
+		var lsn int64
+		if _, err := fmt.Sscanf(filepath.Base(match), snapshotPrefix+"%d"+snapshotSuffix, &lsn); err == nil {
+			lsns = append(lsns, lsn)
+		}
+	}
+	sort.Slice(lsns, func(i, j int) bool { return lsns[i] < lsns[j] })
+	return lsns, nil
+}
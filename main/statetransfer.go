@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+// maxCatchUpEntries caps how many log entries are streamed before falling back to a full snapshot
+const maxCatchUpEntries = 1000
+
+// sendStateTransfer brings a subscribing backup up to date from its last applied LSN.
+// Entries after the backup's LSN are streamed from Log when all of them are still present,
+// otherwise the primary sends a snapshot of store plus any entries that are not committed yet.
+func (a *Actor) sendStateTransfer(ctx actor.Context, target *actor.PID, backupLSN int64) {
+	a.Mu.Lock()
+	commitLSN := a.lastAppliedLSN.Load()
+	highestLSN := a.lsn.Load()
+
+	transfer := &messages.StateTransfer{
+		Term:      a.term.Load(),
+		CommitLsn: commitLSN,
+	}
+
+	from := backupLSN + 1
+	if backupLSN > commitLSN || !a.hasLogRange(from, commitLSN) || commitLSN-backupLSN > maxCatchUpEntries {
+		// Log can't cover the gap, or the backup applied entries from a deposed primary; ship the store instead
+		// An empty store is dropped on the wire like a missing one, so the snapshot is flagged explicitly
+		transfer.HasSnapshot = true
+		transfer.SnapshotLsn = commitLSN
+		transfer.Snapshot = make(map[string]string, len(a.store))
+		transfer.SnapshotVersions = make(map[string]int64, len(a.versions))
+		for key, val := range a.store {
+			transfer.Snapshot[key] = val
+			transfer.SnapshotVersions[key] = a.versions[key]
+		}
+		from = commitLSN + 1
+	}
+
+	// In-flight entries are included so their later Commit finds them in the backup's Log
+	for lsn := from; lsn <= highestLSN; lsn++ {
+		if req, exists := a.Log[lsn]; exists {
+			transfer.Entries = append(transfer.Entries, logEntry(req))
+		}
+	}
+	a.Mu.Unlock()
+
+	log.Printf("%s: Sending StateTransfer to %s (backupLSN=%d, commitLSN=%d, snapshot=%t, entries=%d)\n",
+		role(a.isPrimary), target.String(), backupLSN, commitLSN, transfer.HasSnapshot, len(transfer.Entries))
+	ctx.Request(target, transfer)
+}
+
+// hasLogRange reports whether every LSN in [from, to] is present in Log. Caller must hold Mu.
+func (a *Actor) hasLogRange(from, to int64) bool {
+	for lsn := from; lsn <= to; lsn++ {
+		if _, exists := a.Log[lsn]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// handleStateTransfer installs the snapshot (if any), logs the streamed entries and applies everything committed
+func (a *Actor) handleStateTransfer(ctx actor.Context, msg *messages.StateTransfer) {
+	if !a.acceptPrimaryTerm(ctx, msg.Term) {
+		return
+	}
+	log.Printf("%s: Received StateTransfer (commitLSN=%d, snapshotLSN=%d, entries=%d)\n",
+		role(a.isPrimary), msg.CommitLsn, msg.SnapshotLsn, len(msg.Entries))
+
+	a.observeLSN(msg.CommitLsn)
+	for _, entry := range msg.Entries {
+		a.observeLSN(entry.Lsn)
+	}
+
+	a.catchingUp = false
+	a.Mu.Lock()
+	diverged := a.lastAppliedLSN.Load() > msg.CommitLsn // Applied past the primary under an older term
+	if msg.HasSnapshot && (msg.SnapshotLsn > a.lastAppliedLSN.Load() || diverged) {
+		a.store = make(map[string]string, len(msg.Snapshot))
+		a.versions = make(map[string]int64, len(msg.SnapshotVersions))
+		for key, val := range msg.Snapshot {
+			a.store[key] = val
+			a.versions[key] = msg.SnapshotVersions[key]
+		}
+		a.tombstones = make(map[string]int64) // The snapshot covers every delete
+		a.history = newStoreHistory(a.store, a.versions, msg.SnapshotLsn)
+		a.merkle.rebuild(a.store, a.versions)
+		a.Log = make(map[int64]*Request) // In-flight entries follow in msg.Entries
+		a.pendingMu.Lock()
+		a.pendingCommits = make(map[int64]*Request)
+		a.pendingMu.Unlock()
+		a.lastAppliedLSN.Store(msg.SnapshotLsn)
+		a.snapshotLSN.Store(msg.SnapshotLsn)
+		if err := a.wal.AppendSnapshot(msg.SnapshotLsn, a.store, a.versions, a.config, diverged); err != nil {
+			log.Printf("%s: Failed to log snapshot to WAL: %v\n", role(a.isPrimary), err)
+		}
+		log.Printf("%s: Installed snapshot at LSN %d (%d keys)\n", role(a.isPrimary), msg.SnapshotLsn, len(a.store))
+	}
+	for _, entry := range msg.Entries {
+		req := requestFromEntry(entry)
+		a.Log[entry.Lsn] = req
+		if err := a.wal.AppendEntry(req); err != nil {
+			log.Printf("%s: Failed to log LSN %d to WAL: %v\n", role(a.isPrimary), entry.Lsn, err)
+		}
+	}
+
+	// Queue every committed entry so they apply in LSN order
+	a.pendingMu.Lock()
+	for lsn := a.lastAppliedLSN.Load() + 1; lsn <= msg.CommitLsn; lsn++ {
+		if req, exists := a.Log[lsn]; exists {
+			a.pendingCommits[lsn] = req
+		}
+	}
+	for lsn := range a.pendingCommits {
+		if lsn <= a.lastAppliedLSN.Load() {
+			delete(a.pendingCommits, lsn)
+		}
+	}
+	a.pendingMu.Unlock()
+	a.Mu.Unlock()
+
+	a.applyPendingCommitsToBackup()
+}
+
+// logEntry converts a logged Request into its wire form
+func logEntry(req *Request) *messages.LogEntry {
+	return &messages.LogEntry{
+		Lsn:    req.LSN,
+		Type:   req.Type,
+		Key:    req.Key,
+		Val:    req.Val,
+		Term:   req.Term,
+		Expect: req.Expect,
+		Txn:    txnToWire(req.Ops),
+	}
+}
+
+// requestFromEntry converts a wire log entry back into a Request
+func requestFromEntry(entry *messages.LogEntry) *Request {
+	return &Request{
+		Type:   entry.Type,
+		Key:    entry.Key,
+		Val:    entry.Val,
+		LSN:    entry.Lsn,
+		Term:   entry.Term,
+		Expect: entry.Expect,
+		Ops:    txnFromWire(entry.Txn),
+	}
+}
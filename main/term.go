@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"distributed/messages"
+
+	"github.com/asynkron/protoactor-go/actor"
+)
+
+const termFile = "term"
+
+// SaveTerm durably records the current term and the last term this node voted in
+func (w *WAL) SaveTerm(term, votedTerm int64) error {
+	return writeFileAtomic(filepath.Join(w.opts.Dir, termFile), []byte(fmt.Sprintf("%d %d\n", term, votedTerm)))
+}
+
+// loadTerm reads the persisted term and vote, defaulting to zero for a new node
+func loadTerm(dir string) (int64, int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, termFile))
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var term, votedTerm int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &term, &votedTerm); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", termFile, err)
+	}
+	return term, votedTerm, nil
+}
+
+// persistTerm saves term and votedTerm; callers persist before sending anything that depends on them
+func (a *Actor) persistTerm() {
+	if err := a.wal.SaveTerm(a.term.Load(), a.votedTerm); err != nil {
+		log.Printf("%s: Failed to persist term %d: %v\n", role(a.isPrimary), a.term.Load(), err)
+	}
+}
+
+// setTerm moves to a newer term and persists it
+func (a *Actor) setTerm(term int64) {
+	a.term.Store(term)
+	a.persistTerm()
+}
+
+// acceptPrimaryTerm fences a message sent by a primary. Messages from an older term are rejected
+// with StaleTerm so a deposed primary steps down; a newer term means a new primary, which is followed.
+func (a *Actor) acceptPrimaryTerm(ctx actor.Context, term int64) bool {
+	current := a.term.Load()
+	if term < current {
+		log.Printf("%s: Rejecting %T from %s with stale term %d (current term %d)\n",
+			role(a.isPrimary), ctx.Message(), ctx.Sender().String(), term, current)
+		if ctx.Sender() != nil {
+			ctx.Request(ctx.Sender(), a.staleTerm())
+		}
+		return false
+	}
+	if term > current && ctx.Sender() != nil {
+		if !a.isPrimary && ctx.Sender().Equal(a.primaryPID) {
+			a.setTerm(term) // Already following this primary
+			return true
+		}
+		log.Printf("%s: Saw newer term %d from %s (current term %d)\n",
+			role(a.isPrimary), term, ctx.Sender().String(), current)
+		a.followPrimary(ctx, ctx.Sender(), term)
+	}
+	return true
+}
+
+// acceptReplicaTerm fences a message sent by a replica. A newer term means this node is no
+// longer the rightful primary, so it steps down and the message is dropped.
+func (a *Actor) acceptReplicaTerm(ctx actor.Context, term int64) bool {
+	if term <= a.term.Load() {
+		return true
+	}
+	if a.isPrimary {
+		a.stepDown(ctx, term, nil)
+	} else {
+		a.setTerm(term)
+	}
+	return false
+}
+
+// staleTerm builds the rejection sent to a node using an old term, naming the primary if known
+func (a *Actor) staleTerm() *messages.StaleTerm {
+	reply := &messages.StaleTerm{Term: a.term.Load()}
+	switch {
+	case a.isPrimary:
+		reply.Primary = &messages.Peer{Address: a.self.Address, Id: a.self.Id}
+	case a.primaryPID != nil:
+		reply.Primary = &messages.Peer{Address: a.primaryPID.Address, Id: a.primaryPID.Id}
+	}
+	return reply
+}
+
+// handleStaleTerm steps down after a replica rejected this node's term
+func (a *Actor) handleStaleTerm(ctx actor.Context, msg *messages.StaleTerm) {
+	if msg.Term <= a.term.Load() {
+		return
+	}
+	var primary *actor.PID
+	if msg.Primary != nil {
+		primary = actor.NewPID(msg.Primary.Address, msg.Primary.Id)
+	}
+	a.stepDown(ctx, msg.Term, primary)
+}
+
+// stepDown demotes this node after seeing a newer term, following the new primary when it is known
+func (a *Actor) stepDown(ctx actor.Context, term int64, primary *actor.PID) {
+	log.Printf("%s: Stepping down, saw term %d (current term %d)\n", role(a.isPrimary), term, a.term.Load())
+	if primary != nil && !primary.Equal(a.self) {
+		a.followPrimary(ctx, primary, term)
+		return
+	}
+
+	wasPrimary := a.isPrimary
+	a.setTerm(term)
+	a.Mu.Lock()
+	a.isPrimary = false
+	a.primaryAlive = false
+	a.primaryPID = nil
+	if wasPrimary && len(a.peers) == 0 {
+		a.peers = append(a.peers, a.targets...) // A primary that was never a backup votes with its backups
+	}
+	a.targets = nil
+	a.Mu.Unlock()
+	if wasPrimary {
+		a.abandonPendingRequests()
+	}
+	// With no primary to watch, only the election timer can get this node a new one
+	a.awaitPrimary(term)
+}
+
+// followPrimary makes this node a backup of primary in term and re-subscribes to catch up
+func (a *Actor) followPrimary(ctx actor.Context, primary *actor.PID, term int64) {
+	wasPrimary := a.isPrimary
+	if term > a.term.Load() {
+		a.setTerm(term)
+	}
+	a.awaitStateTransfer()
+
+	a.Mu.Lock()
+	if !primary.Equal(a.primaryPID) {
+		a.primaryHTTP = "" // Learned again from the new primary's Membership
+	}
+	a.isPrimary = false
+	a.candidate = false
+	a.primaryAlive = true
+	a.primaryPID = primary
+	a.targets = []*actor.PID{primary}
+	a.Mu.Unlock()
+	if wasPrimary {
+		a.abandonPendingRequests()
+	}
+
+	a.detector.heard(primary.String()) // Give the new primary a full deadAfter to start heartbeating
+	log.Printf("%s: Following primary %s for term %d\n", role(a.isPrimary), primary.String(), term)
+	ctx.Watch(primary)
+	ctx.Request(primary, &messages.Subscribe{LastLsn: a.lastAppliedLSN.Load(), Term: a.term.Load()})
+}
+
+// awaitStateTransfer holds back commits after switching primaries until the new primary's state
+// transfer has replaced the unapplied entries, which may be from a deposed primary. They stay
+// logged meanwhile: they may be the only other copy of a write the new primary still re-replicates.
+func (a *Actor) awaitStateTransfer() {
+	a.pendingMu.Lock()
+	a.pendingCommits = make(map[int64]*Request)
+	a.pendingMu.Unlock()
+	a.highestSeenLSN = a.lastAppliedLSN.Load()
+	a.catchingUp = true
+	a.subscribedAt = time.Now()
+}
+
+// abandonPendingRequests fails every client waiting on this deposed primary and stops retransmits.
+// Its aborts are forgotten too: a later primary may decide those LSNs again.
+func (a *Actor) abandonPendingRequests() {
+	a.Server.FailPendingRequests(&Response{
+		Success: false,
+		Error:   "Primary stepped down, retry against the new primary",
+		Status:  http.StatusServiceUnavailable,
+	})
+
+	a.retransmitMu.Lock()
+	a.retransmits = make(map[int64]*retransmitEntry)
+	a.aborted = make(map[int64]bool)
+	a.replicaApplied = make(map[string]int64)
+	a.retransmitMu.Unlock()
+}
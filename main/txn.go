@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"distributed/messages"
+)
+
+// TxnOp is one put or delete of a transaction, with its version condition
+type TxnOp struct {
+	Type   string // "WRITE" or "DELETE"
+	Key    string
+	Val    string
+	Expect int64
+}
+
+// HTTPTxnRequest is the JSON body of POST /txn
+type HTTPTxnRequest struct {
+	Ops []HTTPTxnOp `json:"ops"`
+}
+
+// HTTPTxnOp is one operation of POST /txn. ExpectedVersion works like the expected_version query
+// parameter: omitted for no condition, 0 for a key that must not exist, otherwise its version.
+type HTTPTxnOp struct {
+	Op              string `json:"op"` // "put" or "delete"
+	Key             string `json:"key"`
+	Val             string `json:"val,omitempty"`
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+}
+
+// isMutation reports whether a logged entry of type t changes the store when it applies
+func isMutation(t string) bool {
+	return t == "WRITE" || t == "DELETE" || t == "TXN"
+}
+
+// mutations lists the key changes a logged WRITE, DELETE or TXN makes, in order
+func (req *Request) mutations() []TxnOp {
+	if req.Type == "TXN" {
+		return req.Ops
+	}
+	return []TxnOp{{Type: req.Type, Key: req.Key, Val: req.Val, Expect: req.Expect}}
+}
+
+// txnToWire converts a transaction's operations for a log entry, nil if there are none
+func txnToWire(ops []TxnOp) *messages.Transaction {
+	if len(ops) == 0 {
+		return nil
+	}
+	txn := &messages.Transaction{Ops: make([]*messages.TxnOp, 0, len(ops))}
+	for _, op := range ops {
+		txn.Ops = append(txn.Ops, &messages.TxnOp{Type: op.Type, Key: op.Key, Val: op.Val, Expect: op.Expect})
+	}
+	return txn
+}
+
+// txnFromWire converts a log entry's transaction back into its operations
+func txnFromWire(txn *messages.Transaction) []TxnOp {
+	if txn == nil {
+		return nil
+	}
+	ops := make([]TxnOp, 0, len(txn.Ops))
+	for _, op := range txn.Ops {
+		ops = append(ops, TxnOp{Type: op.Type, Key: op.Key, Val: op.Val, Expect: op.Expect})
+	}
+	return ops
+}
+
+// parseTxnOps validates the operations of a POST /txn body. Each key may appear once, so every
+// condition is checked against the store as it was before the transaction.
+func parseTxnOps(body []HTTPTxnOp) ([]TxnOp, error) {
+	if len(body) == 0 {
+		return nil, fmt.Errorf("Transaction needs at least one op")
+	}
+	ops := make([]TxnOp, 0, len(body))
+	seen := make(map[string]bool, len(body))
+	for i, httpOp := range body {
+		op := TxnOp{Key: httpOp.Key, Val: httpOp.Val, Expect: expectAny}
+		switch httpOp.Op {
+		case "put":
+			op.Type = "WRITE"
+			if op.Val == "" {
+				return nil, fmt.Errorf("op %d: put requires val", i)
+			}
+		case "delete":
+			op.Type = "DELETE"
+		default:
+			return nil, fmt.Errorf("op %d: unknown op %q (use put or delete)", i, httpOp.Op)
+		}
+		if op.Key == "" {
+			return nil, fmt.Errorf("op %d: key is required", i)
+		}
+		if seen[op.Key] {
+			return nil, fmt.Errorf("op %d: key %s appears more than once", i, op.Key)
+		}
+		seen[op.Key] = true
+		if version := httpOp.ExpectedVersion; version != nil {
+			switch {
+			case *version < 0:
+				return nil, fmt.Errorf("op %d: expected_version must be a non-negative integer", i)
+			case *version == 0:
+				op.Expect = expectAbsent
+			default:
+				op.Expect = *version
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// handleTxn processes POST /txn: a batch of puts and deletes logged as one LSN, which every node
+// applies under one lock so readers see all of it or none. If any op's condition fails, none apply.
+func (s *Server) handleTxn(w http.ResponseWriter, r *http.Request) {
+	var body HTTPTxnRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.sendError(w, fmt.Sprintf("Invalid transaction body: %v", err), http.StatusBadRequest)
+		return
+	}
+	ops, err := parseTxnOps(body.Ops)
+	if err != nil {
+		s.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Handling TXN request with %d ops", len(ops))
+
+	if !s.routeTxnToShard(w, r, ops) {
+		return
+	}
+
+	req := &Request{Type: "TXN", Ops: ops}
+
+	// Only primary can accept transactions, backups pass them on
+	if !s.replicator.IsPrimary() {
+		s.handleBackupWrite(w, r, req)
+		return
+	}
+
+	s.sendResponse(w, s.submitWrite(req, r.Header.Get(durabilityHeader)))
+}
+
+// routeTxnToShard sends a transaction to the primary of the shard owning its keys. A transaction
+// is only atomic within one replica group, so keys of different shards are refused.
+func (s *Server) routeTxnToShard(w http.ResponseWriter, r *http.Request, ops []TxnOp) bool {
+	m := s.shards.Load()
+	if m == nil {
+		return true
+	}
+	owner := m.Owner(ops[0].Key)
+	for _, op := range ops[1:] {
+		if other := m.Owner(op.Key); other.ID != owner.ID {
+			s.sendError(w, fmt.Sprintf("Transaction spans shards %s (key %s) and %s (key %s)", owner.ID, ops[0].Key, other.ID, op.Key),
+				http.StatusBadRequest)
+			return false
+		}
+	}
+	return s.routeToShard(w, r, ops[0].Key)
+}
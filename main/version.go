@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A key's version is the LSN of the write that last set it. A write or delete can carry a
+// condition on the version, which every replica checks when it applies the entry: all of them
+// apply in LSN order from the same state, so they agree on whether it took effect.
+const (
+	expectAny     int64 = 0  // Unconditional
+	expectAbsent  int64 = -1 // expected_version=0: only if the key doesn't exist
+	expectPresent int64 = -2 // If-Match: *: only if the key exists
+)
+
+// versionMatches reports whether a key at version (exists false if it isn't in the store) meets expect
+func versionMatches(expect, version int64, exists bool) bool {
+	switch expect {
+	case expectAny:
+		return true
+	case expectAbsent:
+		return !exists
+	case expectPresent:
+		return exists
+	}
+	return exists && version == expect
+}
+
+// parseExpect reads a write's condition from the If-Match header or the expected_version query parameter
+func parseExpect(r *http.Request) (int64, error) {
+	expect := expectAny
+	if match := strings.TrimSpace(r.Header.Get("If-Match")); match != "" {
+		if match == "*" {
+			expect = expectPresent
+		} else {
+			version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(match, "W/"), `"`), 10, 64)
+			if err != nil || version <= 0 {
+				return 0, fmt.Errorf("If-Match must be * or a version returned by GET, got %q", match)
+			}
+			expect = version
+		}
+	}
+
+	if param := r.URL.Query().Get("expected_version"); param != "" {
+		version, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || version < 0 {
+			return 0, fmt.Errorf("expected_version must be a non-negative integer")
+		}
+		fromParam := version
+		if version == 0 {
+			fromParam = expectAbsent
+		}
+		if expect != expectAny && expect != fromParam {
+			return 0, fmt.Errorf("If-Match and expected_version disagree")
+		}
+		expect = fromParam
+	}
+	return expect, nil
+}
+
+// conditionFailed is the 412 answer to a write whose condition didn't hold at its LSN
+func conditionFailed(key string, version int64, exists bool) *Response {
+	current := "the key does not exist"
+	if exists {
+		current = fmt.Sprintf("the key is at version %d", version)
+	}
+	return &Response{
+		Success: false,
+		Key:     key,
+		Error:   "Precondition failed: " + current,
+		Status:  http.StatusPreconditionFailed,
+		Version: version,
+	}
+}
+
+// mutationTarget is a store committed mutations apply to, which keeps versions and tombstones in
+// step with it: the primary-backup actor, a Raft node and WAL replay each are one
+type mutationTarget interface {
+	lookup(key string) (version int64, exists bool)
+	setKey(key, val string, lsn int64)
+	deleteKey(key string, lsn int64)
+}
+
+// applyMutation applies a committed WRITE, DELETE or TXN at lsn to target if every condition in it
+// holds, and otherwise applies none of it and returns the 412 answer for the first that failed.
+// Callers hold the target's lock, so readers see all of a transaction or none of it.
+func applyMutation(target mutationTarget, req *Request, lsn int64) *Response {
+	ops := req.mutations()
+	for _, op := range ops {
+		if version, exists := target.lookup(op.Key); !versionMatches(op.Expect, version, exists) {
+			return conditionFailed(op.Key, version, exists)
+		}
+	}
+	for _, op := range ops {
+		if op.Type == "DELETE" {
+			target.deleteKey(op.Key, lsn)
+		} else {
+			target.setKey(op.Key, op.Val, lsn)
+		}
+	}
+	return nil
+}
+
+// lookup is key's version and whether it is in the store. Caller must hold Mu.
+func (a *Actor) lookup(key string) (int64, bool) {
+	_, exists := a.store[key]
+	return a.versions[key], exists
+}
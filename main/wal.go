@@ -0,0 +1,490 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"distributed/messages"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// FsyncPolicy controls when appended WAL records are flushed to stable storage
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"   // fsync after every append
+	FsyncInterval FsyncPolicy = "interval" // fsync from a background ticker
+	FsyncNever    FsyncPolicy = "never"    // leave flushing to the OS
+)
+
+// WAL record kinds
+const (
+	walEntry    = "ENTRY"    // A Write/Read logged at an LSN
+	walCommit   = "COMMIT"   // An LSN applied to the store
+	walSnapshot = "SNAPSHOT" // A full store installed at an LSN
+	walReset    = "RESET"    // A full store installed over a diverged one, even at a lower LSN
+)
+
+const (
+	walHeaderSize    = 8 // 4 byte length + 4 byte CRC32
+	walMaxRecordSize = 64 << 20
+	walSegmentPrefix = "wal-"
+	walSegmentSuffix = ".log"
+)
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WALOptions configures where and how the write-ahead log is stored
+type WALOptions struct {
+	Dir           string
+	SegmentSize   int64 // Rotate to a new segment once the active one reaches this size
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+// WALState is the node state rebuilt by replaying the WAL
+type WALState struct {
+	Log            map[int64]*Request
+	Store          map[string]string
+	LastAppliedLSN int64
+	SnapshotLSN    int64
+	Versions       map[string]int64 // Key => LSN of the write that last set it
+	Config         *Request         // Latest committed CONFIG entry, nil if membership never changed
+	History        *storeHistory    // Versions of every key since the snapshot
+	Tombstones     map[string]int64 // Keys deleted since the snapshot => LSN of the delete
+	Term           int64            // Persisted election term
+	VotedTerm      int64            // Last term this node voted in
+}
+
+// WAL is a segmented, checksummed append-only log of replication records
+type WAL struct {
+	mu      sync.Mutex
+	opts    WALOptions
+	file    *os.File // Active segment
+	segment int      // Index of the active segment
+	size    int64    // Bytes in the active segment
+	dirty   bool     // Unsynced appends (interval policy)
+}
+
+// OpenWAL replays every segment in opts.Dir and opens the last one for appending
+func OpenWAL(opts WALOptions) (*WAL, *WALState, error) {
+	switch opts.Fsync {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, nil, fmt.Errorf("unknown fsync policy %q", opts.Fsync)
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, nil, err
+	}
+
+	segments, err := walSegments(opts.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &WAL{opts: opts}
+	replay := newWALReplay()
+	if err := replay.readSnapshot(opts.Dir); err != nil {
+		return nil, nil, err
+	}
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		size, err := replay.readSegment(w.segmentPath(segment), last)
+		if err != nil {
+			return nil, nil, err
+		}
+		w.segment, w.size = segment, size
+	}
+	if len(segments) == 0 {
+		w.segment = 1
+	}
+
+	if err := w.openSegment(); err != nil {
+		return nil, nil, err
+	}
+	if opts.Fsync == FsyncInterval {
+		go w.syncLoop()
+	}
+
+	state := replay.state()
+	if state.Term, state.VotedTerm, err = loadTerm(opts.Dir); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("WAL: Replayed %d segment(s) from %s (lastAppliedLSN=%d, keys=%d, logged=%d, term=%d)\n",
+		len(segments), opts.Dir, state.LastAppliedLSN, len(state.Store), len(state.Log), state.Term)
+	return w, state, nil
+}
+
+// AppendEntry logs a Write/Read at its LSN; callers must not Ack until this returns nil
+func (w *WAL) AppendEntry(req *Request) error {
+	return w.append(entryRecord(req))
+}
+
+// AppendEntries logs a batch of entries with a single sync
+func (w *WAL) AppendEntries(reqs []*Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, req := range reqs {
+		if err := w.writeLocked(entryRecord(req)); err != nil {
+			return err
+		}
+	}
+	return w.syncLocked()
+}
+
+func entryRecord(req *Request) *messages.WalRecord {
+	return &messages.WalRecord{
+		Kind:   walEntry,
+		Lsn:    req.LSN,
+		Type:   req.Type,
+		Key:    req.Key,
+		Val:    req.Val,
+		Term:   req.Term,
+		Expect: req.Expect,
+		Txn:    txnToWire(req.Ops),
+	}
+}
+
+// requestFromRecord turns a logged entry back into its request
+func requestFromRecord(record *messages.WalRecord) *Request {
+	return &Request{Type: record.Type, Key: record.Key, Val: record.Val, LSN: record.Lsn, Term: record.Term, Expect: record.Expect, Ops: txnFromWire(record.Txn)}
+}
+
+// AppendCommit logs that an LSN was applied to the store
+func (w *WAL) AppendCommit(lsn int64) error {
+	return w.append(&messages.WalRecord{Kind: walCommit, Lsn: lsn})
+}
+
+// AppendCommits logs that a range of LSNs was applied, with a single sync
+func (w *WAL) AppendCommits(lsns []int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, lsn := range lsns {
+		if err := w.writeLocked(&messages.WalRecord{Kind: walCommit, Lsn: lsn}); err != nil {
+			return err
+		}
+	}
+	return w.syncLocked()
+}
+
+// AppendSnapshot logs a full store and its key versions installed at an LSN, with the latest committed
+// config change since the entries that held it are gone. With reset it replaces a diverged store on
+// replay even if that was restored from a snapshot at a higher LSN.
+func (w *WAL) AppendSnapshot(lsn int64, store map[string]string, versions map[string]int64, config *Request, reset bool) error {
+	record := &messages.WalRecord{Kind: walSnapshot, Lsn: lsn, Store: store, Versions: versions}
+	if reset {
+		record.Kind = walReset
+	}
+	if config != nil {
+		record.Config = entryRecord(config)
+	}
+	return w.append(record)
+}
+
+func (w *WAL) append(record *messages.WalRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.appendLocked(record)
+}
+
+// appendLocked writes one record to the active segment and syncs it per the policy. Caller must hold mu.
+func (w *WAL) appendLocked(record *messages.WalRecord) error {
+	if err := w.writeLocked(record); err != nil {
+		return err
+	}
+	return w.syncLocked()
+}
+
+// writeLocked writes one record to the active segment without syncing. Caller must hold mu.
+func (w *WAL) writeLocked(record *messages.WalRecord) error {
+	buf, err := encodeWALRecord(record)
+	if err != nil {
+		return err
+	}
+
+	if w.size > 0 && w.size+int64(len(buf)) > w.opts.SegmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.file.Write(buf); err != nil {
+		return err
+	}
+	w.size += int64(len(buf))
+	return nil
+}
+
+// syncLocked applies the fsync policy to records written so far. Caller must hold mu.
+func (w *WAL) syncLocked() error {
+	switch w.opts.Fsync {
+	case FsyncAlways:
+		return w.file.Sync()
+	case FsyncInterval:
+		w.dirty = true
+	}
+	return nil
+}
+
+// encodeWALRecord frames a record as length, CRC32 and protobuf payload
+func encodeWALRecord(record *messages.WalRecord) ([]byte, error) {
+	payload, err := proto.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, walTable))
+	copy(buf[walHeaderSize:], payload)
+	return buf, nil
+}
+
+// rotate seals the active segment and starts the next one. Caller must hold mu.
+func (w *WAL) rotate() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.segment++
+	w.size = 0
+	return w.openSegment()
+}
+
+func (w *WAL) openSegment() error {
+	file, err := os.OpenFile(w.segmentPath(w.segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	return nil
+}
+
+// syncLoop flushes dirty appends for the interval fsync policy
+func (w *WAL) syncLoop() {
+	ticker := time.NewTicker(w.opts.FsyncInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		w.mu.Lock()
+		if w.dirty {
+			if err := w.file.Sync(); err != nil {
+				log.Printf("WAL: fsync failed: %v\n", err)
+			}
+			w.dirty = false
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *WAL) segmentPath(segment int) string {
+	return filepath.Join(w.opts.Dir, fmt.Sprintf("%s%010d%s", walSegmentPrefix, segment, walSegmentSuffix))
+}
+
+// restoreFromWAL seeds the actor with the state replayed at startup
+func (a *Actor) restoreFromWAL(state *WALState) {
+	a.Log = state.Log
+	a.store = state.Store
+	a.versions = state.Versions
+	a.config = state.Config
+	if a.isPrimary && state.Config != nil {
+		// Later changes replace the -backups a restarted primary would otherwise wait for
+		a.adoptMembers(configMembers(state.Config))
+		log.Printf("Primary: Restored membership %v from config change %q at LSN %d\n", a.memberAddresses(), state.Config.Key, state.Config.LSN)
+	}
+	a.history = state.History
+	a.tombstones = state.Tombstones
+	a.merkle.rebuild(a.store, a.versions)
+	a.lastAppliedLSN.Store(state.LastAppliedLSN)
+	a.snapshotLSN.Store(state.SnapshotLSN)
+	a.term.Store(state.Term)
+	a.votedTerm = state.VotedTerm
+	// A lost COMMIT record can leave entries backups already applied past LastAppliedLSN,
+	// so their LSNs are never handed out again
+	highest := state.LastAppliedLSN
+	for lsn := range state.Log {
+		highest = max(highest, lsn)
+	}
+	a.lsn.Store(highest)
+}
+
+// walSegments lists segment indexes in dir in ascending order
+func walSegments(dir string) ([]int, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, walSegmentPrefix+"*"+walSegmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+	segments := make([]int, 0, len(matches))
+	for _, match := range matches {
+		var segment int
+		if _, err := fmt.Sscanf(filepath.Base(match), walSegmentPrefix+"%d"+walSegmentSuffix, &segment); err == nil {
+			segments = append(segments, segment)
+		}
+	}
+	sort.Ints(segments)
+	return segments, nil
+}
+
+// walReplay accumulates records while segments are read back in order
+type walReplay struct {
+	log         map[int64]*Request
+	committed   map[int64]bool
+	store       map[string]string
+	versions    map[string]int64
+	snapshotLSN int64
+	config      *Request         // Latest committed CONFIG entry
+	tombstones  map[string]int64 // Set up by state, which applies the committed entries
+	history     *storeHistory
+}
+
+func newWALReplay() *walReplay {
+	return &walReplay{
+		log:       make(map[int64]*Request),
+		committed: make(map[int64]bool),
+		store:     make(map[string]string),
+		versions:  make(map[string]int64),
+	}
+}
+
+// readSegment replays one segment and returns its valid size. A torn or corrupt tail
+// is truncated on the last segment (an interrupted append) and is an error anywhere else.
+func (r *walReplay) readSegment(path string, last bool) (int64, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var offset int64
+	header := make([]byte, walHeaderSize)
+	for {
+		record, n, err := readWALRecord(file, header)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			if !last {
+				return 0, fmt.Errorf("%s: corrupt record at offset %d: %w", path, offset, err)
+			}
+			log.Printf("WAL: Truncating %s at offset %d: %v\n", path, offset, err)
+			return offset, file.Truncate(offset)
+		}
+		r.apply(record)
+		offset += n
+	}
+}
+
+func readWALRecord(file *os.File, header []byte) (*messages.WalRecord, int64, error) {
+	if _, err := io.ReadFull(file, header); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[0:4])
+	checksum := binary.LittleEndian.Uint32(header[4:8])
+	if length > walMaxRecordSize {
+		return nil, 0, fmt.Errorf("record length %d exceeds limit", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(file, payload); err != nil {
+		return nil, 0, err
+	}
+	if crc32.Checksum(payload, walTable) != checksum {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+
+	record := &messages.WalRecord{}
+	if err := proto.Unmarshal(payload, record); err != nil {
+		return nil, 0, err
+	}
+	return record, int64(walHeaderSize + len(payload)), nil
+}
+
+func (r *walReplay) apply(record *messages.WalRecord) {
+	switch record.Kind {
+	case walEntry:
+		if record.Lsn <= r.snapshotLSN {
+			return // Already covered by a snapshot
+		}
+		r.log[record.Lsn] = requestFromRecord(record)
+		// READs never get a Commit, and no-ops are only logged once decided. Anything else reusing
+		// the slot waits for its own Commit, whatever the entry it replaced was.
+		r.committed[record.Lsn] = record.Type == "READ" || record.Type == "NOOP"
+	case walCommit:
+		r.committed[record.Lsn] = true
+	case walSnapshot, walReset:
+		if record.Kind == walSnapshot && record.Lsn <= r.snapshotLSN {
+			// Left in a segment that compaction had not removed yet when the node stopped
+			return
+		}
+		// A snapshot replaces everything before it; entries after it are re-logged by the state transfer
+		r.store = make(map[string]string, len(record.Store))
+		for key, val := range record.Store {
+			r.store[key] = val
+		}
+		r.versions = make(map[string]int64, len(record.Versions))
+		for key, version := range record.Versions {
+			r.versions[key] = version
+		}
+		r.snapshotLSN = record.Lsn
+		if record.Config != nil {
+			r.config = requestFromRecord(record.Config)
+		}
+		r.log = make(map[int64]*Request)
+		r.committed = make(map[int64]bool)
+	}
+}
+
+func (r *walReplay) lookup(key string) (int64, bool) {
+	_, exists := r.store[key]
+	return r.versions[key], exists
+}
+
+func (r *walReplay) setKey(key, val string, lsn int64) {
+	r.store[key] = val
+	r.versions[key] = lsn
+	r.history.record(key, lsn, val, false)
+	delete(r.tombstones, key)
+}
+
+func (r *walReplay) deleteKey(key string, lsn int64) {
+	delete(r.store, key)
+	delete(r.versions, key)
+	r.tombstones[key] = lsn
+	r.history.record(key, lsn, "", true)
+}
+
+// state applies committed entries in LSN order, stopping at the first gap. An entry whose condition
+// fails changes nothing, as it didn't when it first applied either.
+func (r *walReplay) state() *WALState {
+	applied := r.snapshotLSN
+	r.tombstones = make(map[string]int64)
+	r.history = newStoreHistory(r.store, r.versions, r.snapshotLSN)
+	for {
+		req, exists := r.log[applied+1]
+		if !exists || !r.committed[applied+1] {
+			break
+		}
+		applied++
+		switch {
+		case isMutation(req.Type):
+			applyMutation(r, req, applied)
+		case req.Type == "CONFIG":
+			r.config = req
+		}
+	}
+	return &WALState{Log: r.log, Store: r.store, Versions: r.versions, History: r.history, LastAppliedLSN: applied, SnapshotLSN: r.snapshotLSN, Config: r.config, Tombstones: r.tombstones}
+}